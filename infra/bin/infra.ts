@@ -25,6 +25,13 @@ const clickStreamService = new ClickstreamServiceStack(
   {
     api: gatewayStack.api,
     tableName: Config.table.clickstream.name,
+    cursorSecret: Config.cursor.secret,
+    retention: Config.table.clickstream.retention,
+    topClicksIndex: Config.table.clickstream.topClicksIndex,
+    visitorIndex: Config.table.clickstream.visitorIndex,
+    sessionIndex: Config.table.clickstream.sessionIndex,
+    botPolicy: Config.bot?.policy,
+    enrichStages: Config.enrich?.stages,
     env: {
       region: Config.aws.region,
     },
@@ -14,9 +14,22 @@ interface IConfig {
   auth: {
     token: string;
   };
+  cursor: {
+    secret: string;
+  };
+  bot?: {
+    policy: string;
+  };
+  enrich?: {
+    stages: string;
+  };
   table: {
     clickstream: {
       name: string;
+      retention?: string;
+      topClicksIndex?: boolean;
+      visitorIndex?: boolean;
+      sessionIndex?: boolean;
     };
   };
 }
@@ -44,12 +57,27 @@ const schema = joi
         token: joi.string().required(),
       })
       .required(),
+    cursor: joi
+      .object({
+        secret: joi.string().required(),
+      })
+      .required(),
+    bot: joi.object({
+      policy: joi.string().valid('flag', 'drop').required(),
+    }),
+    enrich: joi.object({
+      stages: joi.string().required(),
+    }),
 
     table: joi
       .object({
         clickstream: joi
           .object({
             name: joi.string().required(),
+            retention: joi.string(),
+            topClicksIndex: joi.boolean(),
+            visitorIndex: joi.boolean(),
+            sessionIndex: joi.boolean(),
           })
           .required(),
       })
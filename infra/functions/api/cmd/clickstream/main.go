@@ -4,13 +4,17 @@ import (
 	"context"
 	"net/http"
 	"time"
+	// embeds the zoneinfo database, the provided runtime does not ship one.
+	_ "time/tzdata"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/constant"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/handler"
+	sessionhandler "github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/handler"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/middleware"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
@@ -43,14 +47,39 @@ func init() {
 		AllowOrigins:  []string{"*"},
 		AllowMethods:  []string{"*"},
 		AllowHeaders:  []string{"*"},
-		ExposeHeaders: []string{"Content-Length"},
+		ExposeHeaders: []string{"Content-Length", "X-Next-Cursor"},
 		MaxAge:        12 * time.Hour,
 	}))
 	r.Use(middleware.RecoveryWithSlog(logger, true))
 
+	idempotency := middleware.Idempotency(
+		logger,
+		middleware.NewDynamoDBIdempotencyStore(handler.TableName, handler.IdempotencySubjectAttr),
+		constant.IdempotencyTTL,
+		handler.ControllerTimeout+constant.IdempotencyLeaseMargin,
+		constant.IdempotencyMaxBodySize,
+	)
+
 	rg := r.Group("/v1/clickstream")
-	rg.POST("/:path", handler.CreateClickEventController)
+	rg.POST("/_batch", idempotency, handler.BatchCreateClickEventsController)
+	rg.POST("/:path", idempotency, handler.CreateClickEventController)
+	rg.GET("/_top", handler.GetTopPathsController)
+	rg.GET("/_funnel", handler.GetFunnelController)
 	rg.GET("/:path", handler.GetClickStreamController)
+	rg.GET("/:path/count", handler.CountClickStreamController)
+	rg.GET("/:path/series", handler.GetClickSeriesController)
+	rg.GET("/:path/export", handler.ExportClickStreamController)
+	rg.GET("/:path/heatmap", handler.GetHeatmapController)
+	rg.GET("/:path/histogram", handler.GetHistogramController)
+	rg.GET("/:path/uniques", handler.GetUniquesController)
+	rg.GET("/:path/dwell", handler.GetDwellTimeController)
+	rg.PUT("/:path/_shards", handler.PromoteShardsController)
+	rg.DELETE("/_subjects/:visitorId", handler.EraseSubjectController)
+	rg.GET("/_erasures/:jobId", handler.GetErasureController)
+
+	sg := r.Group("/v1/sessions")
+	sg.GET("", sessionhandler.ListSessionsController)
+	sg.GET("/:id", sessionhandler.GetSessionController)
 
 	// setup ginLambda
 	ginLambda = ginadapter.NewV2(r)
@@ -63,7 +92,7 @@ func LambdaHandler(ctx context.Context, req events.APIGatewayV2HTTPRequest) (eve
 			Headers: map[string]string{
 				"Access-Control-Allow-Origin":  "*",
 				"Access-Control-Allow-Methods": "POST, GET, PUT, DELETE, OPTIONS",
-				"Access-Control-Allow-Headers": "Content-Type, Authorization, X-Amzn-Trace-Id, X-Requested-With",
+				"Access-Control-Allow-Headers": "Content-Type, Authorization, X-Amzn-Trace-Id, X-Requested-With, Idempotency-Key",
 				"Access-Control-Max-Age":       "3600",
 			},
 		}, nil
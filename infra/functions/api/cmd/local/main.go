@@ -13,8 +13,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/constant"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/handler"
+	sessionhandler "github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/handler"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/middleware"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/pubsub"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
 	"github.com/joho/godotenv"
 )
@@ -47,16 +49,45 @@ func init() {
 		UTC: false,
 	}))
 	r.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"http://localhost:3000"},
-		AllowMethods: []string{"POST, GET, PUT, DELETE, OPTIONS"},
-		AllowHeaders: []string{"Content-Type", "Authorization", "X-Amzn-Trace-Id", "X-Requested-With"},
-		MaxAge:       12 * time.Hour,
+		AllowOrigins:  []string{"http://localhost:3000"},
+		AllowMethods:  []string{"POST, GET, PUT, DELETE, OPTIONS"},
+		AllowHeaders:  []string{"Content-Type", "Authorization", "X-Amzn-Trace-Id", "X-Requested-With", "Idempotency-Key"},
+		ExposeHeaders: []string{"X-Next-Cursor"},
+		MaxAge:        12 * time.Hour,
 	}))
 	r.Use(middleware.RecoveryWithSlog(logger, true))
 
+	idempotency := middleware.Idempotency(
+		logger,
+		middleware.NewDynamoDBIdempotencyStore(handler.TableName, handler.IdempotencySubjectAttr),
+		constant.IdempotencyTTL,
+		handler.ControllerTimeout+constant.IdempotencyLeaseMargin,
+		constant.IdempotencyMaxBodySize,
+	)
+
+	handler.UseLiveHub(pubsub.NewMemoryHub(handler.LiveSubscriberBuffer))
+
 	rg := r.Group("/v1/clickstream")
-	rg.POST("/:path", handler.CreateClickEventController)
+	rg.POST("/_batch", idempotency, handler.BatchCreateClickEventsController)
+	rg.POST("/:path", idempotency, handler.CreateClickEventController)
+	rg.GET("/_top", handler.GetTopPathsController)
+	rg.GET("/_funnel", handler.GetFunnelController)
 	rg.GET("/:path", handler.GetClickStreamController)
+	rg.GET("/:path/count", handler.CountClickStreamController)
+	rg.GET("/:path/series", handler.GetClickSeriesController)
+	rg.GET("/:path/export", handler.ExportClickStreamController)
+	rg.GET("/:path/heatmap", handler.GetHeatmapController)
+	rg.GET("/:path/histogram", handler.GetHistogramController)
+	rg.GET("/:path/uniques", handler.GetUniquesController)
+	rg.GET("/:path/dwell", handler.GetDwellTimeController)
+	rg.PUT("/:path/_shards", handler.PromoteShardsController)
+	rg.GET("/:path/live", handler.LiveClickStreamController)
+	rg.DELETE("/_subjects/:visitorId", handler.EraseSubjectController)
+	rg.GET("/_erasures/:jobId", handler.GetErasureController)
+
+	sg := r.Group("/v1/sessions")
+	sg.GET("", sessionhandler.ListSessionsController)
+	sg.GET("/:id", sessionhandler.GetSessionController)
 }
 
 func main() {
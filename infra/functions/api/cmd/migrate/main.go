@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/handler"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/joho/godotenv"
+)
+
+const (
+	isProd bool = false
+)
+
+// migrate rewrites stored click events to the latest schema version and gives events
+// written before retention their expiry, so it needs the CLICKSTREAM_RETENTION of the service.
+// It is safe to stop and run again; items already at the latest version and with an expiry are skipped.
+func main() {
+	segments := flag.Int("segments", 4, "number of parallel scan segments")
+	dryRun := flag.Bool("dry-run", false, "upgrade items without writing them back")
+	flag.Parse()
+
+	logger := slogger.Init(isProd)
+
+	if err := godotenv.Load(); err != nil {
+		logger.Info("no .env file, using the environment")
+	}
+
+	o11y.InitXray(logger)
+
+	logger.Info("migrating click-events", "schemaVersion", clickevent.CurrentSchemaVersion, "segments", *segments, "dryRun", *dryRun)
+	if err := run(*segments, *dryRun); err != nil {
+		logger.Error("migration failed", "err", err)
+		os.Exit(1)
+	}
+}
+
+func run(segments int, dryRun bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	ctx, seg := o11y.BeginSegment(ctx, "MigrateClickEvents")
+	defer seg.Close(nil)
+
+	_, err := handler.MigrateClickEventsService(ctx, segments, dryRun)
+	return err
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/handler"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+const (
+	isProd bool = true
+)
+
+func init() {
+	// setup logger
+	logger := slogger.Init(isProd)
+	logger.Info("initializing...")
+
+	// setup o11y
+	o11y.InitXray(logger)
+}
+
+// worker consumes the DynamoDB stream of the clickstream table.
+func main() {
+	lambda.Start(handler.ConsumeTableStreamController)
+}
@@ -0,0 +1,26 @@
+package clickevent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Filter hides click events that are past their retention but not swept by TTL yet,
+// and events flagged as bots unless includeBots is set. Events written before retention
+// have no expiry until the migration gives them one, and are shown until then.
+// The extra condition, when given, is joined with AND. It adds its values to the given map.
+func Filter(extra string, includeBots bool, values map[string]types.AttributeValue) *string {
+	values[":now"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)}
+
+	filter := "(attribute_not_exists(expiresAt) OR expiresAt > :now)"
+	if !includeBots {
+		filter += " AND attribute_not_exists(isBot)"
+	}
+	if extra != "" {
+		filter = extra + " AND " + filter
+	}
+	return aws.String(filter)
+}
@@ -0,0 +1,82 @@
+// Package clickevent holds the item keys, read filter and schema upgrades of stored click events,
+// shared by every slice that reads them.
+package clickevent
+
+import (
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+const SKPrefix = "CLICK#EVENT#"
+
+var (
+	ErrInvalidTimeRange = errors.New("invalid time range")
+
+	minULID = ulid.ULID{}
+	maxULID = ulid.ULID{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+)
+
+func SK(id string) string {
+	return SKPrefix + id
+}
+
+// SKRange converts optional from/to bounds (RFC3339 timestamps or ULIDs) into an inclusive SK range.
+// Since event ids are ULIDs, the SK order is the creation order.
+func SKRange(from, to string) (string, string, error) {
+	lo, err := parseBound(from, minULID)
+	if err != nil {
+		return "", "", err
+	}
+	hi, err := parseBound(to, maxULID)
+	if err != nil {
+		return "", "", err
+	}
+	if lo.Compare(hi) > 0 {
+		return "", "", errors.Wrap(ErrInvalidTimeRange, "from is later than to")
+	}
+
+	return SK(lo.String()), SK(hi.String()), nil
+}
+
+// ParseTimeBound parses a RFC3339 timestamp or a ULID into a time.
+func ParseTimeBound(value string) (time.Time, error) {
+	if id, err := ulid.ParseStrict(value); err == nil {
+		return ulid.Time(id.Time()), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrInvalidTimeRange, "%v is neither RFC3339 nor ULID", value)
+	}
+
+	return t, nil
+}
+
+// parseBound keeps ULIDs as they are and pads timestamps with the given entropy,
+// so a lower bound sorts before and an upper bound sorts after every id of that millisecond.
+func parseBound(value string, fill ulid.ULID) (ulid.ULID, error) {
+	if value == "" {
+		return fill, nil
+	}
+
+	if id, err := ulid.ParseStrict(value); err == nil {
+		return id, nil
+	}
+
+	t, err := ParseTimeBound(value)
+	if err != nil {
+		return fill, err
+	}
+	if t.Before(ulid.Time(0)) || t.After(ulid.Time(ulid.MaxTime())) {
+		return fill, errors.Wrapf(ErrInvalidTimeRange, "%v is out of range", value)
+	}
+
+	id := fill
+	if err := id.SetTime(ulid.Timestamp(t)); err != nil {
+		return fill, errors.Wrap(ErrInvalidTimeRange, err.Error())
+	}
+
+	return id, nil
+}
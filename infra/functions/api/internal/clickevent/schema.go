@@ -0,0 +1,87 @@
+package clickevent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+// CurrentSchemaVersion is the version of the ClickEvent item written today.
+// Items written before versioning have no schemaVersion attribute and are version 1.
+const CurrentSchemaVersion = 2
+
+var ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+
+// ItemUpgrade transforms an item of one schema version into the next one in place.
+type ItemUpgrade func(item map[string]types.AttributeValue) error
+
+// itemUpgrades holds the upgrade from each version to the next.
+// A shape change bumps CurrentSchemaVersion and registers the upgrade from the previous version here.
+var itemUpgrades = map[int]ItemUpgrade{
+	1: upgradeV1,
+}
+
+func ItemSchemaVersion(item map[string]types.AttributeValue) int {
+	v, ok := item["schemaVersion"].(*types.AttributeValueMemberN)
+	if !ok {
+		return 1
+	}
+	n, err := strconv.Atoi(v.Value)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// UpgradeItem brings the item to CurrentSchemaVersion in place and reports whether it changed.
+func UpgradeItem(item map[string]types.AttributeValue) (bool, error) {
+	version := ItemSchemaVersion(item)
+	if version < 1 || version > CurrentSchemaVersion {
+		return false, errors.Wrapf(ErrUnsupportedSchemaVersion, "%v", item["schemaVersion"])
+	}
+
+	for v := version; v < CurrentSchemaVersion; v++ {
+		upgrade, ok := itemUpgrades[v]
+		if !ok {
+			return false, errors.Wrapf(ErrUnsupportedSchemaVersion, "no upgrade from %v", v)
+		}
+		if err := upgrade(item); err != nil {
+			return false, errors.Wrapf(err, "failed to upgrade from %v", v)
+		}
+	}
+	item["schemaVersion"] = &types.AttributeValueMemberN{Value: strconv.Itoa(CurrentSchemaVersion)}
+
+	return version != CurrentSchemaVersion, nil
+}
+
+// UnmarshalItems upgrades the click event items before unmarshalling them into out,
+// so old items read as the current model in every slice.
+func UnmarshalItems(items []map[string]types.AttributeValue, out interface{}) error {
+	for _, item := range items {
+		if _, err := UpgradeItem(item); err != nil {
+			return err
+		}
+	}
+	return attributevalue.UnmarshalListOfMaps(items, out)
+}
+
+// upgradeV1 fills createdAt, which the first items could miss, from the time in the ULID.
+func upgradeV1(item map[string]types.AttributeValue) error {
+	if _, ok := item["createdAt"]; ok {
+		return nil
+	}
+	id, ok := item["id"].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil
+	}
+	parsed, err := ulid.ParseStrict(id.Value)
+	if err != nil {
+		return err
+	}
+	item["createdAt"] = &types.AttributeValueMemberS{Value: ulid.Time(parsed.Time()).UTC().Format(time.RFC3339)}
+	return nil
+}
@@ -4,4 +4,10 @@ import "time"
 
 const (
 	GracefulShutdownTimeout = 5 * time.Second
+	IdempotencyTTL          = 24 * time.Hour
+	// IdempotencyLeaseMargin is added to the controller timeout for the lease of an in-flight key,
+	// after which a retry can take over the key of a request that never finished.
+	IdempotencyLeaseMargin = 5 * time.Second
+	// IdempotencyMaxBodySize matches the payload limit of a synchronous Lambda invocation.
+	IdempotencyMaxBodySize = 6 << 20
 )
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+)
+
+const (
+	HeatPKPrefix  = "CLICK#HEAT#"
+	HeatSKPrefix  = "CELL#"
+	HeatmapCols   = 40
+	HeatmapRows   = 80
+	BucketMobile  = "mobile"
+	BucketTablet  = "tablet"
+	BucketDesktop = "desktop"
+
+	tabletMinWidth  = 768
+	desktopMinWidth = 1024
+)
+
+// ViewportBucket groups viewports by width, since layouts change at these breakpoints.
+func ViewportBucket(width int) string {
+	switch {
+	case width >= desktopMinWidth:
+		return BucketDesktop
+	case width >= tabletMinWidth:
+		return BucketTablet
+	default:
+		return BucketMobile
+	}
+}
+
+// HeatPK puts the bucket first, so a path can not collide with another path and bucket.
+func HeatPK(bucket, path string) string {
+	return HeatPKPrefix + bucket + "#PATH#" + path
+}
+
+func HeatSK(row, col int) string {
+	return fmt.Sprintf("%v%03d#%03d", HeatSKPrefix, row, col)
+}
+
+func ParseHeatSK(sk string) (int, int, bool) {
+	var row, col int
+	if _, err := fmt.Sscanf(sk, HeatSKPrefix+"%03d#%03d", &row, &col); err != nil {
+		return 0, 0, false
+	}
+	if row < 0 || row >= HeatmapRows || col < 0 || col >= HeatmapCols {
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+// HeatmapCell normalizes page coordinates by the document size into a grid cell,
+// so clicks on documents of different sizes land on comparable cells.
+func HeatmapCell(x, y int, document *Document) (int, int, bool) {
+	if document == nil || document.Width <= 0 || document.Height <= 0 {
+		return 0, 0, false
+	}
+	if x < 0 || y < 0 || x > document.Width || y > document.Height {
+		return 0, 0, false
+	}
+
+	col := x * HeatmapCols / document.Width
+	row := y * HeatmapRows / document.Height
+	// a click on the far edge belongs to the last cell.
+	return min(row, HeatmapRows-1), min(col, HeatmapCols-1), true
+}
+
+type HeatmapCounter struct {
+	PK    string `json:"PK" dynamodbav:"PK"`
+	SK    string `json:"SK" dynamodbav:"SK"`
+	Count int64  `json:"count" dynamodbav:"count"`
+}
+
+type Heatmap struct {
+	Path   string
+	Bucket string
+	Cells  [][]int64
+	Total  int64
+	Max    int64
+}
+
+func NewHeatmap(path, bucket string) Heatmap {
+	cells := make([][]int64, HeatmapRows)
+	for i := range cells {
+		cells[i] = make([]int64, HeatmapCols)
+	}
+	return Heatmap{Path: path, Bucket: bucket, Cells: cells}
+}
+
+func (d *Heatmap) Add(row, col int, count int64) {
+	d.Cells[row][col] += count
+	d.Total += count
+	d.Max = max(d.Max, d.Cells[row][col])
+}
+
+func (d *Heatmap) DTO() dto.Heatmap {
+	return dto.Heatmap{
+		Path:   d.Path,
+		Bucket: d.Bucket,
+		Rows:   HeatmapRows,
+		Cols:   HeatmapCols,
+		Cells:  d.Cells,
+		Total:  d.Total,
+		Max:    d.Max,
+	}
+}
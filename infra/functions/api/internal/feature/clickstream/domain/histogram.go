@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/pkg/errors"
+)
+
+const (
+	Interval5m = "5m"
+	Interval1h = "1h"
+	Interval1d = "1d"
+
+	HistogramSourceCounters = "counters"
+	HistogramSourceEvents   = "events"
+)
+
+var ErrInvalidTimeZone = errors.New("invalid time zone")
+
+// LoadTimeZone resolves an IANA time zone name, an empty name is UTC.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidTimeZone, "%v", name)
+	}
+	return loc, nil
+}
+
+// TruncateBucket returns the start of the local-time bucket that contains t.
+// Sub-day buckets follow the wall clock, so the repeated hour of a DST fall-back
+// becomes two buckets and the skipped hour of a spring-forward none.
+// Day buckets start at local midnight and last 23 to 25 hours around DST changes.
+func TruncateBucket(interval string, t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	if interval == Interval1d {
+		year, month, day := t.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(IntervalDuration(interval)).Add(-shift).In(loc)
+}
+
+// NextBucket returns the start of the bucket following the one that starts at t.
+func NextBucket(interval string, t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	if interval == Interval1d {
+		year, month, day := t.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	}
+	return TruncateBucket(interval, t.Add(IntervalDuration(interval)), loc)
+}
+
+func IntervalDuration(interval string) time.Duration {
+	switch interval {
+	case Interval5m:
+		return time.Minute * 5
+	case Interval1d:
+		return time.Hour * 24
+	default:
+		return time.Hour
+	}
+}
+
+type HistogramBucket struct {
+	Start time.Time
+	End   time.Time
+	Count int64
+}
+
+type Histogram struct {
+	Path     string
+	Interval string
+	TimeZone string
+	Source   string
+	Exact    bool
+	Buckets  []HistogramBucket
+}
+
+// NewHistogram lays out the zero-filled buckets between the buckets of from and to, inclusive.
+func NewHistogram(path, interval string, loc *time.Location, from, to time.Time) Histogram {
+	histogram := Histogram{
+		Path:     path,
+		Interval: interval,
+		TimeZone: loc.String(),
+		Exact:    true,
+	}
+
+	last := TruncateBucket(interval, to, loc)
+	for t := TruncateBucket(interval, from, loc); !t.After(last); t = NextBucket(interval, t, loc) {
+		histogram.Buckets = append(histogram.Buckets, HistogramBucket{
+			Start: t,
+			End:   NextBucket(interval, t, loc),
+		})
+	}
+
+	return histogram
+}
+
+// HourAligned reports whether every bucket starts on a UTC hour, so hour counters can fill it.
+func (d *Histogram) HourAligned() bool {
+	for _, bucket := range d.Buckets {
+		if !bucket.Start.Truncate(time.Hour).Equal(bucket.Start) {
+			return false
+		}
+	}
+	return true
+}
+
+// Add counts n clicks into the bucket that contains t, it ignores times out of range.
+func (d *Histogram) Add(t time.Time, n int64) {
+	lo, hi := 0, len(d.Buckets)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if d.Buckets[mid].End.After(t) {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo < len(d.Buckets) && !t.Before(d.Buckets[lo].Start) {
+		d.Buckets[lo].Count += n
+	}
+}
+
+func (d *Histogram) DTO() dto.Histogram {
+	buckets := make([]dto.HistogramBucket, 0, len(d.Buckets))
+	var total int64
+	for _, b := range d.Buckets {
+		buckets = append(buckets, dto.HistogramBucket{
+			Start: b.Start.Format(time.RFC3339),
+			Count: b.Count,
+		})
+		total += b.Count
+	}
+	return dto.Histogram{
+		Path:     d.Path,
+		Interval: d.Interval,
+		TimeZone: d.TimeZone,
+		Source:   d.Source,
+		Exact:    d.Exact,
+		Total:    total,
+		Buckets:  buckets,
+	}
+}
@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/pkg/errors"
+)
+
+const (
+	EventPKPrefix   = "CLICK#EVENT#PATH#"
+	EventSKPrefix   = clickevent.SKPrefix
+	CounterPKPrefix = "CLICK#COUNT#PATH#"
+	UniquePKPrefix  = "CLICK#UNIQUE#PATH#"
+	DwellPKPrefix   = "CLICK#DWELL#PATH#"
+	ShardPKPrefix   = "CLICK#SHARD#PATH#"
+	ShardSK         = "CLICK#SHARD"
+	TopPKPrefix     = "CLICK#TOP#"
+	TopSKPrefix     = "PATH#"
+	ErasurePKPrefix = "CLICK#ERASURE#"
+	ErasureSK       = "CLICK#ERASURE"
+
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+	GranularityWeek = "week"
+
+	hourSKPrefix = "HOUR#"
+	daySKPrefix  = "DAY#"
+	weekSKPrefix = "WEEK#"
+	hourLayout   = "2006-01-02T15"
+	dayLayout    = "2006-01-02"
+)
+
+var (
+	ErrInvalidTimeRange = clickevent.ErrInvalidTimeRange
+	ErrErasureNotFound  = errors.New("erasure not found")
+	ErrShardDemotion    = errors.New("shard count can not be lowered")
+)
+
+func ErasurePK(jobID string) string {
+	return ErasurePKPrefix + jobID
+}
+
+func EventPK(path string) string {
+	return EventPKPrefix + path
+}
+
+// EventShardPK returns the partition of an event shard.
+// Shard 0 is the unsuffixed partition every path started with, so unsharded events stay readable.
+func EventShardPK(path string, shard int) string {
+	return ShardedPK(EventPK(path), shard)
+}
+
+// ShardedPK suffixes a partition with a shard the same way as the event partitions.
+// The aggregates of a sharded path are split by the shard of the events they count.
+func ShardedPK(pk string, shard int) string {
+	if shard == 0 {
+		return pk
+	}
+	return fmt.Sprintf("%v#%d", pk, shard)
+}
+
+// EventShard returns the shard of the event from its partition, 0 for the legacy partition.
+func EventShard(event *ClickEvent) int {
+	suffix, ok := strings.CutPrefix(event.PK, EventPK(event.Path)+"#")
+	if !ok {
+		return 0
+	}
+	shard, err := strconv.Atoi(suffix)
+	if err != nil || shard < 0 {
+		return 0
+	}
+	return shard
+}
+
+func ShardPK(path string) string {
+	return ShardPKPrefix + path
+}
+
+func EventSK(id string) string {
+	return clickevent.SK(id)
+}
+
+func CounterPK(path string) string {
+	return CounterPKPrefix + path
+}
+
+func UniquePK(path string) string {
+	return UniquePKPrefix + path
+}
+
+func DwellPK(path string) string {
+	return DwellPKPrefix + path
+}
+
+// PeriodSK returns the sort key of the UTC hour, day or ISO week bucket that contains t.
+func PeriodSK(granularity string, t time.Time) string {
+	t = t.UTC()
+	switch granularity {
+	case GranularityWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%v%04d-W%02d", weekSKPrefix, year, week)
+	case GranularityDay:
+		return daySKPrefix + t.Format(dayLayout)
+	default:
+		return hourSKPrefix + t.Format(hourLayout)
+	}
+}
+
+// TopPK returns the leaderboard partition of a period shard.
+// A path always hashes to the same shards, see TopShard.
+func TopPK(period string, shard int) string {
+	return fmt.Sprintf("%v%v#%d", TopPKPrefix, period, shard)
+}
+
+// TopShard returns the leaderboard shard of an event shard of the path.
+// Event shard 0 keeps the shard the path hashes to, the others follow it, so a sharded path
+// spreads its clicks over several leaderboard partitions and is summed up on read.
+func TopShard(path string, eventShard, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(path))
+	return int((h.Sum32() + uint32(eventShard)) % uint32(shards))
+}
+
+func TopSK(path string) string {
+	return TopSKPrefix + path
+}
+
+// TruncatePeriod returns the start of the UTC hour or day bucket that contains t.
+func TruncatePeriod(granularity string, t time.Time) time.Time {
+	t = t.UTC()
+	if granularity == GranularityDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// NextPeriod returns the start of the bucket following the one that starts at t.
+func NextPeriod(granularity string, t time.Time) time.Time {
+	if granularity == GranularityDay {
+		return t.AddDate(0, 0, 1)
+	}
+	return t.Add(time.Hour)
+}
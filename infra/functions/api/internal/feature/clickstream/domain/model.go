@@ -1,19 +1,456 @@
 package domain
 
-import "github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+)
 
 type ClickEvent struct {
-	PK        string `json:"PK"`
-	SK        string `json:"SK"`
-	ID        string `json:"id"`
-	Path      string `json:"path"`
-	CreatedAt string `json:"createdAt"`
+	PK              string         `json:"PK" dynamodbav:"PK"`
+	SK              string         `json:"SK" dynamodbav:"SK"`
+	SchemaVersion   int            `json:"schemaVersion" dynamodbav:"schemaVersion"`
+	ID              string         `json:"id" dynamodbav:"id"`
+	Path            string         `json:"path" dynamodbav:"path"`
+	SessionID       string         `json:"sessionId,omitempty" dynamodbav:"sessionId,omitempty"`
+	VisitorID       string         `json:"visitorId,omitempty" dynamodbav:"visitorId,omitempty"`
+	Selector        string         `json:"selector,omitempty" dynamodbav:"selector,omitempty"`
+	Referrer        string         `json:"referrer,omitempty" dynamodbav:"referrer,omitempty"`
+	Viewport        *Viewport      `json:"viewport,omitempty" dynamodbav:"viewport,omitempty"`
+	X               *int           `json:"x,omitempty" dynamodbav:"x,omitempty"`
+	Y               *int           `json:"y,omitempty" dynamodbav:"y,omitempty"`
+	Document        *Document      `json:"document,omitempty" dynamodbav:"document,omitempty"`
+	ClientTimestamp string         `json:"clientTimestamp,omitempty" dynamodbav:"clientTimestamp,omitempty"`
+	Properties      map[string]any `json:"properties,omitempty" dynamodbav:"properties,omitempty"`
+	CreatedAt       string         `json:"createdAt" dynamodbav:"createdAt"`
+	ExpiresAt       int64          `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
+	IsBot           bool           `json:"isBot,omitempty" dynamodbav:"isBot,omitempty"`
+	BotReason       string         `json:"botReason,omitempty" dynamodbav:"botReason,omitempty"`
+	Browser         string         `json:"browser,omitempty" dynamodbav:"browser,omitempty"`
+	BrowserVersion  string         `json:"browserVersion,omitempty" dynamodbav:"browserVersion,omitempty"`
+	OS              string         `json:"os,omitempty" dynamodbav:"os,omitempty"`
+	OSVersion       string         `json:"osVersion,omitempty" dynamodbav:"osVersion,omitempty"`
+	Device          string         `json:"device,omitempty" dynamodbav:"device,omitempty"`
+	Engine          string         `json:"engine,omitempty" dynamodbav:"engine,omitempty"`
+	Country         string         `json:"country,omitempty" dynamodbav:"country,omitempty"`
+	Region          string         `json:"region,omitempty" dynamodbav:"region,omitempty"`
+	City            string         `json:"city,omitempty" dynamodbav:"city,omitempty"`
+	UTMSource       string         `json:"utmSource,omitempty" dynamodbav:"utmSource,omitempty"`
+	UTMMedium       string         `json:"utmMedium,omitempty" dynamodbav:"utmMedium,omitempty"`
+	UTMCampaign     string         `json:"utmCampaign,omitempty" dynamodbav:"utmCampaign,omitempty"`
+	UTMTerm         string         `json:"utmTerm,omitempty" dynamodbav:"utmTerm,omitempty"`
+	UTMContent      string         `json:"utmContent,omitempty" dynamodbav:"utmContent,omitempty"`
+}
+
+type Viewport struct {
+	Width  int `json:"width" dynamodbav:"width"`
+	Height int `json:"height" dynamodbav:"height"`
+}
+
+type Document struct {
+	Width  int `json:"width" dynamodbav:"width"`
+	Height int `json:"height" dynamodbav:"height"`
+}
+
+func NewClickEvent(req *dto.ClickEvent) ClickEvent {
+	event := ClickEvent{
+		PK:              EventPK(req.Path),
+		SK:              EventSK(req.ID),
+		SchemaVersion:   clickevent.CurrentSchemaVersion,
+		ID:              req.ID,
+		Path:            req.Path,
+		SessionID:       req.SessionID,
+		VisitorID:       req.VisitorID,
+		Selector:        req.Selector,
+		Referrer:        req.Referrer,
+		X:               req.X,
+		Y:               req.Y,
+		ClientTimestamp: req.ClientTimestamp,
+		Properties:      req.Properties,
+		CreatedAt:       req.CreatedAt,
+		IsBot:           req.IsBot,
+		BotReason:       req.BotReason,
+		Browser:         req.Browser,
+		BrowserVersion:  req.BrowserVersion,
+		OS:              req.OS,
+		OSVersion:       req.OSVersion,
+		Device:          req.Device,
+		Engine:          req.Engine,
+		Country:         req.Country,
+		Region:          req.Region,
+		City:            req.City,
+		UTMSource:       req.UTMSource,
+		UTMMedium:       req.UTMMedium,
+		UTMCampaign:     req.UTMCampaign,
+		UTMTerm:         req.UTMTerm,
+		UTMContent:      req.UTMContent,
+	}
+	if req.Viewport != nil {
+		event.Viewport = &Viewport{
+			Width:  req.Viewport.Width,
+			Height: req.Viewport.Height,
+		}
+	}
+	if req.Document != nil {
+		event.Document = &Document{
+			Width:  req.Document.Width,
+			Height: req.Document.Height,
+		}
+	}
+	return event
 }
 
 func (d *ClickEvent) DTO() dto.ClickEvent {
-	return dto.ClickEvent{
-		ID:        d.ID,
+	event := dto.ClickEvent{
+		ID:              d.ID,
+		Path:            d.Path,
+		SessionID:       d.SessionID,
+		VisitorID:       d.VisitorID,
+		Selector:        d.Selector,
+		Referrer:        d.Referrer,
+		X:               d.X,
+		Y:               d.Y,
+		ClientTimestamp: d.ClientTimestamp,
+		Properties:      d.Properties,
+		CreatedAt:       d.CreatedAt,
+		IsBot:           d.IsBot,
+		Browser:         d.Browser,
+		BrowserVersion:  d.BrowserVersion,
+		OS:              d.OS,
+		OSVersion:       d.OSVersion,
+		Device:          d.Device,
+		Engine:          d.Engine,
+		Country:         d.Country,
+		Region:          d.Region,
+		City:            d.City,
+		UTMSource:       d.UTMSource,
+		UTMMedium:       d.UTMMedium,
+		UTMCampaign:     d.UTMCampaign,
+		UTMTerm:         d.UTMTerm,
+		UTMContent:      d.UTMContent,
+	}
+	if d.Viewport != nil {
+		event.Viewport = &dto.Viewport{
+			Width:  d.Viewport.Width,
+			Height: d.Viewport.Height,
+		}
+	}
+	if d.Document != nil {
+		event.Document = &dto.Document{
+			Width:  d.Document.Width,
+			Height: d.Document.Height,
+		}
+	}
+	return event
+}
+
+type ClickStreamPage struct {
+	Items      []ClickEvent
+	NextCursor string
+}
+
+func (p *ClickStreamPage) DTO() []dto.ClickEvent {
+	events := make([]dto.ClickEvent, 0, len(p.Items))
+	for i := range p.Items {
+		events = append(events, p.Items[i].DTO())
+	}
+	return events
+}
+
+type ClickCount struct {
+	Count        int64
+	Exact        bool
+	ScannedPages int
+	NextCursor   string
+}
+
+func (d *ClickCount) DTO() dto.ClickCount {
+	return dto.ClickCount{
+		Count:        d.Count,
+		Exact:        d.Exact,
+		ScannedPages: d.ScannedPages,
+		NextCursor:   d.NextCursor,
+	}
+}
+
+const (
+	BatchStatusAccepted = "accepted"
+	BatchStatusRejected = "rejected"
+)
+
+type ClickCounter struct {
+	PK     string `json:"PK" dynamodbav:"PK"`
+	SK     string `json:"SK" dynamodbav:"SK"`
+	Path   string `json:"path" dynamodbav:"path"`
+	Period string `json:"period" dynamodbav:"period"`
+	Count  int64  `json:"count" dynamodbav:"count"`
+}
+
+type ClickSeriesPoint struct {
+	Time  time.Time
+	Count int64
+}
+
+type ClickSeries struct {
+	Granularity string
+	Points      []ClickSeriesPoint
+}
+
+func (d *ClickSeries) DTO() dto.ClickSeries {
+	points := make([]dto.ClickSeriesPoint, 0, len(d.Points))
+	for _, p := range d.Points {
+		points = append(points, dto.ClickSeriesPoint{
+			Time:  p.Time.Format(time.RFC3339),
+			Count: p.Count,
+		})
+	}
+	return dto.ClickSeries{
+		Granularity: d.Granularity,
+		Points:      points,
+	}
+}
+
+// UniqueSketch holds the serialized HyperLogLog of the visitors of a path in a period.
+// Version guards the read-modify-write of the sketch.
+type UniqueSketch struct {
+	PK      string `json:"PK" dynamodbav:"PK"`
+	SK      string `json:"SK" dynamodbav:"SK"`
+	Path    string `json:"path" dynamodbav:"path"`
+	Period  string `json:"period" dynamodbav:"period"`
+	Sketch  []byte `json:"hll" dynamodbav:"hll"`
+	Version int64  `json:"version" dynamodbav:"version"`
+}
+
+type Uniques struct {
+	Path          string
+	Granularity   string
+	From          time.Time
+	To            time.Time
+	Periods       int
+	Estimate      uint64
+	StandardError float64
+}
+
+// DTO reports the estimate with a two standard error bound, about 95% confidence.
+func (d *Uniques) DTO() dto.Uniques {
+	margin := 2 * d.StandardError * float64(d.Estimate)
+	return dto.Uniques{
+		Path:          d.Path,
+		Granularity:   d.Granularity,
+		From:          d.From.Format(time.RFC3339),
+		To:            d.To.Format(time.RFC3339),
+		Periods:       d.Periods,
+		Estimate:      d.Estimate,
+		StandardError: d.StandardError,
+		Lower:         uint64(math.Max(0, float64(d.Estimate)-margin)),
+		Upper:         uint64(float64(d.Estimate) + margin),
+	}
+}
+
+// DwellSketch holds the serialized t-digest of the dwell times on a path in a period, in milliseconds.
+type DwellSketch struct {
+	PK      string `json:"PK" dynamodbav:"PK"`
+	SK      string `json:"SK" dynamodbav:"SK"`
+	Path    string `json:"path" dynamodbav:"path"`
+	Period  string `json:"period" dynamodbav:"period"`
+	Sketch  []byte `json:"tdigest" dynamodbav:"tdigest"`
+	Version int64  `json:"version" dynamodbav:"version"`
+}
+
+type DwellTime struct {
+	Path        string
+	Granularity string
+	From        time.Time
+	To          time.Time
+	Periods     int
+	Count       int64
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+}
+
+func (d *DwellTime) DTO() dto.DwellTime {
+	return dto.DwellTime{
+		Path:        d.Path,
+		Granularity: d.Granularity,
+		From:        d.From.Format(time.RFC3339),
+		To:          d.To.Format(time.RFC3339),
+		Periods:     d.Periods,
+		Count:       d.Count,
+		P50:         d.P50.Milliseconds(),
+		P90:         d.P90.Milliseconds(),
+		P99:         d.P99.Milliseconds(),
+	}
+}
+
+const (
+	ShardStrategyRandom = "random"
+	ShardStrategyHash   = "hash"
+)
+
+// ShardConfig spreads the events of a hot path over several partitions.
+// Shards are only ever added, so reads over shards [0, Shards) always see every event.
+type ShardConfig struct {
+	PK        string `json:"PK" dynamodbav:"PK"`
+	SK        string `json:"SK" dynamodbav:"SK"`
+	Path      string `json:"path" dynamodbav:"path"`
+	Shards    int    `json:"shards" dynamodbav:"shards"`
+	Strategy  string `json:"strategy" dynamodbav:"strategy"`
+	UpdatedAt string `json:"updatedAt" dynamodbav:"updatedAt"`
+}
+
+// DefaultShardConfig is the config of a path that was never promoted, a single legacy partition.
+func DefaultShardConfig(path string) ShardConfig {
+	return ShardConfig{
+		PK:       ShardPK(path),
+		SK:       ShardSK,
+		Path:     path,
+		Shards:   1,
+		Strategy: ShardStrategyRandom,
+	}
+}
+
+// ShardFor picks the shard of an event. The hash strategy keeps the events of a visitor together,
+// the random strategy spreads them evenly.
+func (d *ShardConfig) ShardFor(event *ClickEvent) int {
+	if d.Shards <= 1 {
+		return 0
+	}
+	if d.Strategy == ShardStrategyHash && event.VisitorID != "" {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(event.VisitorID))
+		return int(h.Sum32() % uint32(d.Shards))
+	}
+	return rand.IntN(d.Shards)
+}
+
+func (d *ShardConfig) DTO() dto.ShardConfig {
+	return dto.ShardConfig{
 		Path:      d.Path,
-		CreatedAt: d.CreatedAt,
+		Shards:    d.Shards,
+		Strategy:  d.Strategy,
+		UpdatedAt: d.UpdatedAt,
 	}
 }
+
+type TopPath struct {
+	PK     string `json:"PK" dynamodbav:"PK"`
+	SK     string `json:"SK" dynamodbav:"SK"`
+	Path   string `json:"path" dynamodbav:"path"`
+	Period string `json:"period" dynamodbav:"period"`
+	Clicks int64  `json:"clicks" dynamodbav:"clicks"`
+}
+
+type TopPaths struct {
+	Period string
+	Paths  []TopPath
+}
+
+func (d *TopPaths) DTO() dto.TopPaths {
+	paths := make([]dto.TopPath, 0, len(d.Paths))
+	for _, p := range d.Paths {
+		paths = append(paths, dto.TopPath{
+			Path:   p.Path,
+			Clicks: p.Clicks,
+		})
+	}
+	return dto.TopPaths{
+		Period: d.Period,
+		Paths:  paths,
+	}
+}
+
+type FunnelStep struct {
+	Path     string
+	Visitors int
+}
+
+type Funnel struct {
+	Steps     []FunnelStep
+	Truncated bool
+}
+
+func (d *Funnel) DTO() dto.Funnel {
+	steps := make([]dto.FunnelStep, 0, len(d.Steps))
+	for i, step := range d.Steps {
+		s := dto.FunnelStep{
+			Path:     step.Path,
+			Visitors: step.Visitors,
+		}
+		if i > 0 {
+			prev := d.Steps[i-1].Visitors
+			s.DropOff = prev - step.Visitors
+			if prev > 0 {
+				s.StepConversion = float64(step.Visitors) / float64(prev)
+			}
+		} else if step.Visitors > 0 {
+			s.StepConversion = 1
+		}
+		if first := d.Steps[0].Visitors; first > 0 {
+			s.TotalConversion = float64(step.Visitors) / float64(first)
+		}
+		steps = append(steps, s)
+	}
+	return dto.Funnel{
+		Steps:     steps,
+		Truncated: d.Truncated,
+	}
+}
+
+const (
+	ErasureStatusRunning   = "running"
+	ErasureStatusCompleted = "completed"
+)
+
+// Erasure is the receipt of a subject erasure job.
+// SubjectID is only kept while the job is running so its passes can find the subject;
+// the receipt keeps the SubjectHash as proof of what was erased.
+type Erasure struct {
+	PK          string `json:"PK" dynamodbav:"PK"`
+	SK          string `json:"SK" dynamodbav:"SK"`
+	JobID       string `json:"jobId" dynamodbav:"jobId"`
+	SubjectID   string `json:"subjectId,omitempty" dynamodbav:"subjectId,omitempty"`
+	SubjectHash string `json:"subjectHash" dynamodbav:"subjectHash"`
+	Status      string `json:"status" dynamodbav:"status"`
+	Deleted     int64  `json:"deleted" dynamodbav:"deleted"`
+	Passes      int64  `json:"passes" dynamodbav:"passes"`
+	RequestedAt string `json:"requestedAt" dynamodbav:"requestedAt"`
+	CompletedAt string `json:"completedAt,omitempty" dynamodbav:"completedAt,omitempty"`
+}
+
+func NewErasure(jobID, visitorID string, now time.Time) Erasure {
+	sum := sha256.Sum256([]byte(visitorID))
+	return Erasure{
+		PK:          ErasurePK(jobID),
+		SK:          ErasureSK,
+		JobID:       jobID,
+		SubjectID:   visitorID,
+		SubjectHash: hex.EncodeToString(sum[:]),
+		Status:      ErasureStatusRunning,
+		RequestedAt: now.UTC().Format(time.RFC3339),
+	}
+}
+
+func (d *Erasure) DTO() dto.Erasure {
+	return dto.Erasure{
+		JobID:       d.JobID,
+		SubjectHash: d.SubjectHash,
+		Status:      d.Status,
+		Deleted:     d.Deleted,
+		RequestedAt: d.RequestedAt,
+		CompletedAt: d.CompletedAt,
+	}
+}
+
+type Migration struct {
+	Scanned   int64
+	Migrated  int64
+	Conflicts int64
+	Failed    int64
+}
@@ -1,7 +1,247 @@
 package dto
 
+import "net/http"
+
 type ClickEvent struct {
-	ID        string `json:"id"`
-	Path      string `json:"path" binding:"required" validate:"required"`
-	CreatedAt string `json:"createdAt"`
+	ID              string         `json:"id"`
+	Path            string         `json:"path" binding:"required" validate:"required"`
+	SessionID       string         `json:"sessionId,omitempty" validate:"omitempty,max=128"`
+	VisitorID       string         `json:"visitorId,omitempty" validate:"omitempty,max=128"`
+	Selector        string         `json:"selector,omitempty" validate:"omitempty,max=1024"`
+	Referrer        string         `json:"referrer,omitempty" validate:"omitempty,max=2048"`
+	URL             string         `json:"url,omitempty" validate:"omitempty,max=2048"`
+	Viewport        *Viewport      `json:"viewport,omitempty"`
+	X               *int           `json:"x,omitempty" validate:"omitempty,min=0,max=1000000"`
+	Y               *int           `json:"y,omitempty" validate:"omitempty,min=0,max=1000000"`
+	Document        *Document      `json:"document,omitempty"`
+	ClientTimestamp string         `json:"clientTimestamp,omitempty" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Properties      map[string]any `json:"properties,omitempty" validate:"omitempty,max=50,dive,keys,min=1,max=128,endkeys"`
+	CreatedAt       string         `json:"createdAt"`
+	IsBot           bool           `json:"isBot,omitempty"`
+	Browser         string         `json:"browser,omitempty"`
+	BrowserVersion  string         `json:"browserVersion,omitempty"`
+	OS              string         `json:"os,omitempty"`
+	OSVersion       string         `json:"osVersion,omitempty"`
+	Device          string         `json:"device,omitempty"`
+	Engine          string         `json:"engine,omitempty"`
+	Country         string         `json:"country,omitempty"`
+	Region          string         `json:"region,omitempty"`
+	City            string         `json:"city,omitempty"`
+	UTMSource       string         `json:"utmSource,omitempty"`
+	UTMMedium       string         `json:"utmMedium,omitempty"`
+	UTMCampaign     string         `json:"utmCampaign,omitempty"`
+	UTMTerm         string         `json:"utmTerm,omitempty"`
+	UTMContent      string         `json:"utmContent,omitempty"`
+	BotReason       string         `json:"-"`
+	Client          *ClientInfo    `json:"-"`
+}
+
+// ClientInfo is what the server knows about the sender of an event.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Header    http.Header
+}
+
+type Viewport struct {
+	Width  int `json:"width" validate:"min=0,max=100000"`
+	Height int `json:"height" validate:"min=0,max=100000"`
+}
+
+type Document struct {
+	Width  int `json:"width" validate:"min=0,max=1000000"`
+	Height int `json:"height" validate:"min=0,max=1000000"`
+}
+
+type PageQuery struct {
+	Limit  int32  `form:"limit" json:"limit" validate:"omitempty,min=1,max=1000"`
+	Cursor string `form:"cursor" json:"cursor"`
+}
+
+type ClickStreamQuery struct {
+	PageQuery
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
+
+type ExportQuery struct {
+	Format      string `form:"format" json:"format" validate:"omitempty,oneof=ndjson csv"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+	Cursor      string `form:"cursor" json:"cursor"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
+
+type ClickCountQuery struct {
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+	Cursor      string `form:"cursor" json:"cursor"`
+	MaxPages    int    `form:"maxPages" json:"maxPages" validate:"omitempty,min=1,max=1000"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
+
+type ClickCount struct {
+	Count        int64  `json:"count"`
+	Exact        bool   `json:"exact"`
+	ScannedPages int    `json:"scannedPages"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+}
+
+type ClickEventBatch struct {
+	Events []ClickEvent `json:"events" validate:"required,min=1"`
+}
+
+type BatchResult struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ClickSeriesQuery struct {
+	Granularity string `form:"granularity" json:"granularity" validate:"omitempty,oneof=hour day"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+}
+
+type ClickSeriesPoint struct {
+	Time  string `json:"time"`
+	Count int64  `json:"count"`
+}
+
+type ClickSeries struct {
+	Granularity string             `json:"granularity"`
+	Points      []ClickSeriesPoint `json:"points"`
+}
+
+type HistogramQuery struct {
+	Interval    string `form:"interval" json:"interval" validate:"omitempty,oneof=5m 1h 1d"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+	TZ          string `form:"tz" json:"tz"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
+
+type HistogramBucket struct {
+	Start string `json:"start"`
+	Count int64  `json:"count"`
+}
+
+type Histogram struct {
+	Path     string            `json:"path"`
+	Interval string            `json:"interval"`
+	TimeZone string            `json:"tz"`
+	Source   string            `json:"source"`
+	Exact    bool              `json:"exact"`
+	Total    int64             `json:"total"`
+	Buckets  []HistogramBucket `json:"buckets"`
+}
+
+type UniquesQuery struct {
+	Granularity string `form:"granularity" json:"granularity" validate:"omitempty,oneof=hour day"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+}
+
+type Uniques struct {
+	Path          string  `json:"path"`
+	Granularity   string  `json:"granularity"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Periods       int     `json:"periods"`
+	Estimate      uint64  `json:"estimate"`
+	StandardError float64 `json:"standardError"`
+	Lower         uint64  `json:"lower"`
+	Upper         uint64  `json:"upper"`
+}
+
+type TopPathsQuery struct {
+	Period string `form:"period" json:"period" validate:"omitempty,oneof=day week"`
+	N      int    `form:"n" json:"n" validate:"omitempty,min=1,max=100"`
+	At     string `form:"at" json:"at"`
+}
+
+type DwellTimeQuery struct {
+	Granularity string `form:"granularity" json:"granularity" validate:"omitempty,oneof=hour day"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+}
+
+type DwellTime struct {
+	Path        string `json:"path"`
+	Granularity string `json:"granularity"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Periods     int    `json:"periods"`
+	Count       int64  `json:"count"`
+	P50         int64  `json:"p50Ms"`
+	P90         int64  `json:"p90Ms"`
+	P99         int64  `json:"p99Ms"`
+}
+
+type ShardConfigRequest struct {
+	Shards   int    `json:"shards" validate:"required,min=1,max=64"`
+	Strategy string `json:"strategy" validate:"omitempty,oneof=random hash"`
+}
+
+type ShardConfig struct {
+	Path      string `json:"path"`
+	Shards    int    `json:"shards"`
+	Strategy  string `json:"strategy"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+type TopPath struct {
+	Path   string `json:"path"`
+	Clicks int64  `json:"clicks"`
+}
+
+type TopPaths struct {
+	Period string    `json:"period"`
+	Paths  []TopPath `json:"paths"`
+}
+
+type FunnelQuery struct {
+	Steps       []string `form:"step" json:"steps" validate:"required,min=2,max=10,dive,required"`
+	From        string   `form:"from" json:"from"`
+	To          string   `form:"to" json:"to"`
+	Window      string   `form:"window" json:"window"`
+	IncludeBots bool     `form:"includeBots" json:"includeBots"`
+}
+
+type FunnelStep struct {
+	Path            string  `json:"path"`
+	Visitors        int     `json:"visitors"`
+	DropOff         int     `json:"dropOff"`
+	StepConversion  float64 `json:"stepConversion"`
+	TotalConversion float64 `json:"totalConversion"`
+}
+
+type Funnel struct {
+	Steps     []FunnelStep `json:"steps"`
+	Truncated bool         `json:"truncated"`
+}
+
+type Erasure struct {
+	JobID       string `json:"jobId"`
+	SubjectHash string `json:"subjectHash"`
+	Status      string `json:"status"`
+	Deleted     int64  `json:"deleted"`
+	RequestedAt string `json:"requestedAt"`
+	CompletedAt string `json:"completedAt,omitempty"`
+}
+
+type HeatmapQuery struct {
+	Bucket string `form:"bucket" json:"bucket" validate:"omitempty,oneof=mobile tablet desktop"`
+}
+
+type Heatmap struct {
+	Path   string    `json:"path"`
+	Bucket string    `json:"bucket"`
+	Rows   int       `json:"rows"`
+	Cols   int       `json:"cols"`
+	Cells  [][]int64 `json:"cells"`
+	Total  int64     `json:"total"`
+	Max    int64     `json:"max"`
 }
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	classifier "github.com/haandol/vertical-slice-go-lambda-example/api/pkg/bot"
+	"github.com/pkg/errors"
+)
+
+const (
+	Name = "bot"
+
+	PolicyFlag = "flag"
+	PolicyDrop = "drop"
+
+	DefaultRateLimit = 300
+	RateWindow       = time.Minute
+)
+
+// Enricher tags the events of a batch with the bot verdict of its client,
+// and drops them instead under the drop policy.
+type Enricher struct {
+	classifier *classifier.Classifier
+	policy     string
+}
+
+// New reads BOT_POLICY (flag or drop), BOT_IP_RANGES (comma separated CIDRs)
+// and BOT_RATE_LIMIT (events per RateWindow per IP, 0 to disable).
+func New() (enrich.Enricher, error) {
+	e := &Enricher{policy: PolicyFlag}
+	if policy := os.Getenv("BOT_POLICY"); policy != "" {
+		if policy != PolicyFlag && policy != PolicyDrop {
+			return nil, errors.Errorf("invalid bot policy %v", policy)
+		}
+		e.policy = policy
+	}
+
+	opts := classifier.Options{
+		RateLimit:  DefaultRateLimit,
+		RateWindow: RateWindow,
+	}
+	if ranges := os.Getenv("BOT_IP_RANGES"); ranges != "" {
+		opts.ExtraRanges = strings.Split(ranges, ",")
+	}
+	if limit := os.Getenv("BOT_RATE_LIMIT"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("invalid bot rate limit %v", limit)
+		}
+		opts.RateLimit = n
+	}
+
+	c, err := classifier.NewClassifier(opts)
+	if err != nil {
+		return nil, err
+	}
+	e.classifier = c
+	return e, nil
+}
+
+func (e *Enricher) Name() string {
+	return Name
+}
+
+// Enrich leaves batches without client info, such as ones created internally, unclassified.
+func (e *Enricher) Enrich(ctx context.Context, batch *enrich.Batch) error {
+	if batch.Client == nil {
+		return nil
+	}
+
+	verdict := e.classifier.Classify(&classifier.Request{
+		IP:        batch.Client.IP,
+		UserAgent: batch.Client.UserAgent,
+		Header:    batch.Client.Header,
+	}, len(batch.Events))
+	if verdict.IsBot && e.policy == PolicyDrop {
+		return &enrich.DropError{Reason: verdict.Reason}
+	}
+
+	for _, event := range batch.Events {
+		event.IsBot = verdict.IsBot
+		event.BotReason = verdict.Reason
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PolicyOpen stores the events without the enrichment of a failed stage.
+	PolicyOpen = "open"
+	// PolicyClosed fails the request when the stage fails.
+	PolicyClosed = "closed"
+)
+
+var (
+	ErrDropped       = errors.New("dropped")
+	ErrStageTimeout  = errors.New("enricher timed out")
+	ErrUnknownStage  = errors.New("unknown enricher")
+	ErrInvalidStages = errors.New("invalid enricher stages")
+)
+
+// Batch is the events of one request together with the client that sent them.
+type Batch struct {
+	Client *dto.ClientInfo
+	Events []*dto.ClickEvent
+}
+
+// Enricher adds server-side fields to the events of a batch before they are stored.
+// An enricher works on a copy of the batch that is discarded when it fails or times out,
+// so it may be abandoned mid-way; it must not modify maps or pointers shared with the request.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, batch *Batch) error
+}
+
+// Factory builds an enricher once per process.
+type Factory func() (Enricher, error)
+
+// DropError tells the pipeline to stop and the events to be discarded, regardless of the stage policy.
+type DropError struct {
+	Stage  string
+	Reason string
+}
+
+func (e *DropError) Error() string {
+	return fmt.Sprintf("dropped by %v enricher", e.Stage)
+}
+
+func (e *DropError) Unwrap() error {
+	return ErrDropped
+}
+
+type Stage struct {
+	Enricher Enricher
+	Policy   string
+	Timeout  time.Duration
+}
+
+// Pipeline runs its stages in order, each in its own subsegment and with its own timeout.
+type Pipeline struct {
+	stages []Stage
+}
+
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Stages returns the names of the stages in order.
+func (p *Pipeline) Stages() []string {
+	names := make([]string, 0, len(p.stages))
+	for _, stage := range p.stages {
+		names = append(names, stage.Enricher.Name())
+	}
+	return names
+}
+
+// Run enriches the batch in place. It returns a *DropError when a stage drops the batch,
+// or the error of a failed closed stage.
+func (p *Pipeline) Run(ctx context.Context, batch *Batch) error {
+	for _, stage := range p.stages {
+		err := p.runStage(ctx, stage, batch)
+		if err == nil {
+			continue
+		}
+
+		var drop *DropError
+		if errors.As(err, &drop) {
+			return err
+		}
+		if stage.Policy == PolicyClosed {
+			return errors.Wrapf(err, "%v enricher", stage.Enricher.Name())
+		}
+	}
+
+	return nil
+}
+
+func (p *Pipeline) runStage(ctx context.Context, stage Stage, batch *Batch) error {
+	name := stage.Enricher.Name()
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "enrich",
+		"component", "enricher",
+		"stage", name,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "Enricher:"+name)
+	defer span.Close(nil)
+
+	ctx, cancel := context.WithTimeout(ctx, stage.Timeout)
+	defer cancel()
+
+	work := batch.clone()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.Errorf("panic: %v", r)
+			}
+		}()
+		done <- stage.Enricher.Enrich(ctx, work)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrapf(ErrStageTimeout, "after %v", stage.Timeout)
+	}
+
+	var drop *DropError
+	switch {
+	case err == nil:
+		batch.apply(work)
+	case errors.As(err, &drop):
+		drop.Stage = name
+		instrument.RecordEnrichDropped(logger, drop.Reason, len(batch.Events))
+	default:
+		instrument.RecordEnrichStageError(logger, span, err, stage.Policy)
+	}
+
+	return err
+}
+
+func (b *Batch) clone() *Batch {
+	c := &Batch{Events: make([]*dto.ClickEvent, len(b.Events))}
+	if b.Client != nil {
+		client := *b.Client
+		c.Client = &client
+	}
+	for i, event := range b.Events {
+		copied := *event
+		c.Events[i] = &copied
+	}
+	return c
+}
+
+func (b *Batch) apply(from *Batch) {
+	for i := range b.Events {
+		*b.Events[i] = *from.Events[i]
+	}
+}
+
+// ParseStages builds the stages from a comma separated spec of name[:policy[:timeout]],
+// e.g. "bot,useragent:open:50ms,fingerprint:closed:1s". Stages run in the order they are listed.
+// A stage whose factory fails is left out, since it can not enrich anything.
+func ParseStages(spec string, factories map[string]Factory, defaultTimeout time.Duration) ([]Stage, error) {
+	logger := slogger.New()
+
+	stages := make([]Stage, 0)
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) > 3 {
+			return nil, errors.Wrapf(ErrInvalidStages, "%v", entry)
+		}
+		name := parts[0]
+		factory, ok := factories[name]
+		if !ok {
+			return nil, errors.Wrapf(ErrUnknownStage, "%v", name)
+		}
+		if seen[name] {
+			return nil, errors.Wrapf(ErrInvalidStages, "%v is listed twice", name)
+		}
+		seen[name] = true
+
+		stage := Stage{Policy: PolicyOpen, Timeout: defaultTimeout}
+		if len(parts) > 1 && parts[1] != "" {
+			if parts[1] != PolicyOpen && parts[1] != PolicyClosed {
+				return nil, errors.Wrapf(ErrInvalidStages, "%v has an unknown policy %v", name, parts[1])
+			}
+			stage.Policy = parts[1]
+		}
+		if len(parts) > 2 {
+			timeout, err := time.ParseDuration(parts[2])
+			if err != nil || timeout <= 0 {
+				return nil, errors.Wrapf(ErrInvalidStages, "%v has an invalid timeout %v", name, parts[2])
+			}
+			stage.Timeout = timeout
+		}
+
+		enricher, err := factory()
+		if err != nil {
+			logger.Warn("enricher is disabled", "stage", name, "err", err)
+			continue
+		}
+		stage.Enricher = enricher
+		stages = append(stages, stage)
+	}
+
+	return stages, nil
+}
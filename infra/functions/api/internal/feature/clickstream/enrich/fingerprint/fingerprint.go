@@ -0,0 +1,47 @@
+package fingerprint
+
+import (
+	"context"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/privacy"
+)
+
+const Name = "fingerprint"
+
+// Enricher derives a visitor id from the client for events sent without one.
+// It needs the raw IP, so it runs before the IP is discarded.
+type Enricher struct {
+	fingerprinter *privacy.Fingerprinter
+}
+
+func New(fingerprinter *privacy.Fingerprinter) (enrich.Enricher, error) {
+	return &Enricher{fingerprinter: fingerprinter}, nil
+}
+
+func (e *Enricher) Name() string {
+	return Name
+}
+
+func (e *Enricher) Enrich(ctx context.Context, batch *enrich.Batch) error {
+	if batch.Client == nil || batch.Client.IP == "" {
+		return nil
+	}
+
+	var visitorID string
+	for _, event := range batch.Events {
+		if event.VisitorID != "" {
+			continue
+		}
+		if visitorID == "" {
+			fp, err := e.fingerprinter.Fingerprint(ctx, batch.Client.IP, batch.Client.UserAgent, time.Now())
+			if err != nil {
+				return err
+			}
+			visitorID = fp
+		}
+		event.VisitorID = visitorID
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"context"
+	"os"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/geoip"
+)
+
+const (
+	Name = "geo"
+
+	DefaultDBPath = "GeoLite2-City.mmdb"
+)
+
+// Enricher stores the location of the client IP on the events of a batch.
+type Enricher struct {
+	db *geoip.DB
+}
+
+// New opens the database from GEOIP_DB_PATH, or DefaultDBPath.
+func New() (enrich.Enricher, error) {
+	path := os.Getenv("GEOIP_DB_PATH")
+	if path == "" {
+		path = DefaultDBPath
+	}
+	db, err := geoip.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Enricher{db: db}, nil
+}
+
+func (e *Enricher) Name() string {
+	return Name
+}
+
+// Enrich stores an unknown location for addresses missing from the database.
+func (e *Enricher) Enrich(ctx context.Context, batch *enrich.Batch) error {
+	var location geoip.Location
+	if batch.Client != nil && batch.Client.IP != "" {
+		if l, err := e.db.Lookup(batch.Client.IP); err == nil {
+			location = l
+		}
+	}
+
+	for _, event := range batch.Events {
+		event.Country = location.Country
+		event.Region = location.Region
+		event.City = location.City
+	}
+	return nil
+}
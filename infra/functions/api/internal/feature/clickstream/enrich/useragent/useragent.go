@@ -0,0 +1,39 @@
+package useragent
+
+import (
+	"context"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	parser "github.com/haandol/vertical-slice-go-lambda-example/api/pkg/useragent"
+)
+
+const Name = "useragent"
+
+// Enricher stores the parsed user-agent of the client on the events of a batch.
+// Values sent in the body are always replaced, so they can be trusted for breakdowns.
+type Enricher struct{}
+
+func New() (enrich.Enricher, error) {
+	return &Enricher{}, nil
+}
+
+func (e *Enricher) Name() string {
+	return Name
+}
+
+func (e *Enricher) Enrich(ctx context.Context, batch *enrich.Batch) error {
+	var agent parser.Agent
+	if batch.Client != nil {
+		agent = parser.Parse(batch.Client.UserAgent)
+	}
+
+	for _, event := range batch.Events {
+		event.Browser = agent.Browser
+		event.BrowserVersion = agent.BrowserVersion
+		event.OS = agent.OS
+		event.OSVersion = agent.OSVersion
+		event.Device = agent.Device
+		event.Engine = agent.Engine
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package utm
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+)
+
+const (
+	Name = "utm"
+
+	MaxValueLength = 256
+)
+
+// Enricher stores the campaign parameters of the page URL on each event.
+// Values sent in the body are always replaced, so a campaign can only come from the URL.
+type Enricher struct{}
+
+func New() (enrich.Enricher, error) {
+	return &Enricher{}, nil
+}
+
+func (e *Enricher) Name() string {
+	return Name
+}
+
+func (e *Enricher) Enrich(ctx context.Context, batch *enrich.Batch) error {
+	for _, event := range batch.Events {
+		var query url.Values
+		if u, err := url.Parse(event.URL); err == nil {
+			query = u.Query()
+		}
+
+		event.UTMSource = value(query, "utm_source")
+		event.UTMMedium = value(query, "utm_medium")
+		event.UTMCampaign = value(query, "utm_campaign")
+		event.UTMTerm = value(query, "utm_term")
+		event.UTMContent = value(query, "utm_content")
+	}
+	return nil
+}
+
+func value(query url.Values, key string) string {
+	v := strings.TrimSpace(query.Get(key))
+	if len(v) > MaxValueLength {
+		v = strings.ToValidUTF8(v[:MaxValueLength], "")
+	}
+	return v
+}
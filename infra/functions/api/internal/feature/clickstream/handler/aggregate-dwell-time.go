@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// service.
+// It folds the dwell times of the clicks of a stream batch into the digests,
+// so a digest is rewritten once per batch rather than once per click.
+func AggregateDwellTimeService(ctx context.Context, events []domain.ClickEvent) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "aggregateDwellTime",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "AggregateDwellTimeService")
+	defer span.Close(nil)
+
+	dropped, err := AggregateDwellTimeRepository(ctx, events)
+	if dropped > 0 {
+		instrument.RecordDwellSamplesDropped(logger, span, err, dropped)
+		return nil
+	}
+	if err != nil {
+		instrument.RecordAggregateDwellTimeError(logger, span, err)
+		return err
+	}
+
+	return nil
+}
+
+// secondary adapter.
+// An error before any digest is merged fails the whole batch, which the stream then retries.
+// Digests that can not be merged after that are reported as dropped samples instead.
+func AggregateDwellTimeRepository(ctx context.Context, events []domain.ClickEvent) (int, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "aggregateDwellTime",
+		"component", "repository",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "AggregateDwellTimeRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, err
+	}
+
+	keys, deltas, err := dwellSamples(ctx, client, events)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, err
+	}
+
+	return mergeDwellSamples(ctx, client, keys, deltas)
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// service.
+// It adds the visitors of the clicks of a stream batch to the unique visitor sketches.
+// A failure is returned, so the stream retries the batch until the sketches take it.
+func AggregateUniquesService(ctx context.Context, events []domain.ClickEvent) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "aggregateUniques",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "AggregateUniquesService")
+	defer span.Close(nil)
+
+	if err := AggregateUniquesRepository(ctx, events); err != nil {
+		instrument.RecordAggregateUniquesError(logger, span, err)
+		return err
+	}
+
+	return nil
+}
+
+// secondary adapter.
+func AggregateUniquesRepository(ctx context.Context, events []domain.ClickEvent) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "aggregateUniques",
+		"component", "repository",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "AggregateUniquesRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	if err := applyUniqueSketches(ctx, client, events); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	return nil
+}
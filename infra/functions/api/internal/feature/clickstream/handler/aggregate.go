@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+)
+
+type aggregateKey struct {
+	PK string
+	SK string
+}
+
+type aggregateDelta struct {
+	Attr   string
+	Path   string
+	Period string
+	Count  int64
+}
+
+// clickAggregateUpdates returns the counter and leaderboard increments for the given events.
+// Increments on the same item are merged, since a transaction can touch an item only once.
+// Every item is split by the shard of the event, so a hot path spreads its increments as it spreads its events.
+func clickAggregateUpdates(events []domain.ClickEvent) []types.TransactWriteItem {
+	keys := make([]aggregateKey, 0)
+	deltas := make(map[aggregateKey]*aggregateDelta)
+
+	add := func(key aggregateKey, attr, path, period string) {
+		if delta, ok := deltas[key]; ok {
+			delta.Count++
+			return
+		}
+		keys = append(keys, key)
+		deltas[key] = &aggregateDelta{Attr: attr, Path: path, Period: period, Count: 1}
+	}
+
+	for i := range events {
+		// bots are kept out of the counters, so they never inflate them.
+		if events[i].IsBot {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, events[i].CreatedAt)
+		if err != nil {
+			continue
+		}
+		path := events[i].Path
+		shard := domain.EventShard(&events[i])
+
+		for _, granularity := range []string{domain.GranularityHour, domain.GranularityDay} {
+			period := domain.PeriodSK(granularity, createdAt)
+			add(aggregateKey{PK: domain.ShardedPK(domain.CounterPK(path), shard), SK: period}, "count", path, period)
+		}
+
+		if key, ok := heatmapKey(&events[i]); ok {
+			add(key, "count", path, "")
+		}
+
+		topShard := domain.TopShard(path, shard, TopPathShards)
+		for _, granularity := range []string{domain.GranularityDay, domain.GranularityWeek} {
+			period := domain.PeriodSK(granularity, createdAt)
+			add(aggregateKey{PK: domain.TopPK(period, topShard), SK: domain.TopSK(path)}, "clicks", path, period)
+		}
+	}
+
+	items := make([]types.TransactWriteItem, 0, len(keys))
+	for _, key := range keys {
+		delta := deltas[key]
+		update := &types.Update{
+			TableName: aws.String(TableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: key.PK},
+				"SK": &types.AttributeValueMemberS{Value: key.SK},
+			},
+			UpdateExpression: aws.String("ADD #count :n SET #path = :path"),
+			ExpressionAttributeNames: map[string]string{
+				"#count": delta.Attr,
+				"#path":  "path",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":n":    &types.AttributeValueMemberN{Value: strconv.FormatInt(delta.Count, 10)},
+				":path": &types.AttributeValueMemberS{Value: delta.Path},
+			},
+		}
+		if delta.Period != "" {
+			update.UpdateExpression = aws.String("ADD #count :n SET #path = :path, #period = :period")
+			update.ExpressionAttributeNames["#period"] = "period"
+			update.ExpressionAttributeValues[":period"] = &types.AttributeValueMemberS{Value: delta.Period}
+		}
+		items = append(items, types.TransactWriteItem{Update: update})
+	}
+
+	return items
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/middleware"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrBatchTooLarge = errors.Errorf("batch has more than %d events", BatchMaxEvents)
+
+	errBatchUnprocessed = errors.New("unprocessed after retries")
+	errBatchWriteFailed = errors.New("write failed")
+)
+
+// primary adapter.
+func BatchCreateClickEventsController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "batchCreateClickEvents",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "BatchCreateClickEventsController")
+	defer span.Close(nil)
+
+	var batch dto.ClickEventBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid body",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&batch); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	if len(batch.Events) > BatchMaxEvents {
+		commoninstrument.RecordBadInputError(logger, span, ErrBatchTooLarge)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": ErrBatchTooLarge.Error(),
+		})
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	results := make([]dto.BatchResult, len(batch.Events))
+	valid := make([]*dto.ClickEvent, 0, len(batch.Events))
+	for i := range batch.Events {
+		req := &batch.Events[i]
+		req.ID = ulid.Make().String()
+		req.CreatedAt = now
+
+		results[i] = dto.BatchResult{Index: i, ID: req.ID, Status: domain.BatchStatusAccepted}
+		if err := util.ValidateStruct(req); err != nil {
+			results[i].Status = domain.BatchStatusRejected
+			results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, req)
+	}
+
+	client := &dto.ClientInfo{
+		IP:        middleware.ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		Header:    c.Request.Header,
+	}
+
+	rejected, err := BatchCreateClickEventsService(ctx, client, valid)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to create click-events",
+		})
+		return
+	}
+
+	accepted := 0
+	for i := range results {
+		if reason, ok := rejected[results[i].ID]; ok {
+			results[i].Status = domain.BatchStatusRejected
+			results[i].Error = reason.Error()
+		}
+		if results[i].Status == domain.BatchStatusAccepted {
+			accepted++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     results,
+		"accepted": accepted,
+		"rejected": len(results) - accepted,
+	})
+}
+
+// service.
+// The events of a batch share one client, so they are classified together.
+func BatchCreateClickEventsService(ctx context.Context, client *dto.ClientInfo, reqs []*dto.ClickEvent) (map[string]error, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "batchCreateClickEvents",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "BatchCreateClickEventsService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, reqs)
+
+	if len(reqs) > BatchMaxEvents {
+		return nil, ErrBatchTooLarge
+	}
+
+	if err := enrichClickEvents(ctx, client, reqs...); err != nil {
+		if errors.Is(err, enrich.ErrDropped) {
+			rejected := make(map[string]error, len(reqs))
+			for _, req := range reqs {
+				rejected[req.ID] = err
+			}
+			return rejected, nil
+		}
+		instrument.RecordBatchCreateClickEventsError(logger, span, err)
+		return nil, err
+	}
+
+	rejected, err := BatchCreateClickEventsRepository(ctx, reqs)
+	if err != nil {
+		instrument.RecordBatchCreateClickEventsError(logger, span, err)
+		return rejected, err
+	}
+
+	instrument.RecordBatchCreateClickEventsSuccess(logger, len(reqs)-len(rejected), len(rejected))
+	for _, req := range reqs {
+		if _, ok := rejected[req.ID]; !ok {
+			event := domain.NewClickEvent(req)
+			publishLive(&event)
+		}
+	}
+
+	return rejected, nil
+}
+
+// secondary adapter.
+// It returns the reason for every event id that could not be written.
+func BatchCreateClickEventsRepository(ctx context.Context, reqs []*dto.ClickEvent) (map[string]error, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "batchCreateClickEvents",
+		"component", "repository",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "BatchCreateClickEventsRepository")
+	defer span.Close(nil)
+
+	rejected := make(map[string]error)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return rejected, err
+	}
+
+	events := make([]domain.ClickEvent, 0, len(reqs))
+	for _, req := range reqs {
+		event := domain.NewClickEvent(req)
+		withRetention(&event)
+		if err := withShard(ctx, client, &event); err != nil {
+			commoninstrument.RecordError(logger, span, err)
+		}
+		events = append(events, event)
+	}
+
+	for _, chunk := range batchTransactChunks(events) {
+		// the events of a chunk and their counters are written together so they can never drift apart.
+		items := make([]types.TransactWriteItem, 0, TransactWriteMaxItems)
+		written := make([]domain.ClickEvent, 0, len(chunk))
+		for _, event := range chunk {
+			item, err := attributevalue.MarshalMap(event)
+			if err != nil {
+				rejected[event.ID] = err
+				continue
+			}
+			items = append(items, types.TransactWriteItem{
+				Put: &types.Put{
+					TableName:           aws.String(TableName),
+					Item:                item,
+					ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+				},
+			})
+			written = append(written, event)
+		}
+		if len(written) == 0 {
+			continue
+		}
+		items = append(items, clickAggregateUpdates(written)...)
+
+		if err := transactWriteWithRetry(ctx, client, &dynamodb.TransactWriteItemsInput{
+			TransactItems:      items,
+			ClientRequestToken: aws.String(written[0].ID),
+		}); err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			reason := errBatchWriteFailed
+			if errors.Is(err, errTransactContended) {
+				reason = errBatchUnprocessed
+			}
+			for _, event := range written {
+				rejected[event.ID] = reason
+			}
+			continue
+		}
+	}
+
+	return rejected, nil
+}
+
+// batchTransactChunks splits the events so that the puts and counter updates of a chunk fit in one transaction.
+func batchTransactChunks(events []domain.ClickEvent) [][]domain.ClickEvent {
+	chunks := make([][]domain.ClickEvent, 0)
+	start, size := 0, 0
+	for i := range events {
+		cost := 1 + len(clickAggregateUpdates(events[i:i+1]))
+		if size+cost > TransactWriteMaxItems && i > start {
+			chunks = append(chunks, events[start:i])
+			start, size = i, 0
+		}
+		size += cost
+	}
+	if start < len(events) {
+		chunks = append(chunks, events[start:])
+	}
+	return chunks
+}
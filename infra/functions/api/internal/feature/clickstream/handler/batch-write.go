@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+)
+
+var errTransactContended = errors.New("transaction is contended")
+
+// batchWriteWithRetry sends up to BatchWriteChunkSize requests in one BatchWriteItem call
+// and retries UnprocessedItems with exponential backoff and full jitter.
+// It returns the requests that are still unprocessed after BatchWriteMaxAttempts.
+func batchWriteWithRetry(ctx context.Context, client *dynamodb.Client, requests []types.WriteRequest) ([]types.WriteRequest, error) {
+	pending := requests
+	for attempt := 0; attempt < BatchWriteMaxAttempts && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoff(attempt)); err != nil {
+				return pending, err
+			}
+		}
+
+		output, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{
+				TableName: pending,
+			},
+		})
+		if err != nil {
+			return pending, err
+		}
+
+		pending = output.UnprocessedItems[TableName]
+	}
+
+	return pending, nil
+}
+
+// transactWriteWithRetry retries a transaction that was canceled by a conflicting write or throttling,
+// with the same backoff as batchWriteWithRetry. The ClientRequestToken of the input keeps the retries idempotent.
+func transactWriteWithRetry(ctx context.Context, client *dynamodb.Client, input *dynamodb.TransactWriteItemsInput) error {
+	for attempt := 0; attempt < BatchWriteMaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoff(attempt)); err != nil {
+				return err
+			}
+		}
+
+		_, err := client.TransactWriteItems(ctx, input)
+		if err == nil {
+			return nil
+		}
+		if !transactRetryable(err) {
+			return err
+		}
+	}
+
+	return errTransactContended
+}
+
+func transactRetryable(err error) bool {
+	var inProgress *types.TransactionInProgressException
+	if errors.As(err, &inProgress) {
+		return true
+	}
+
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+	for _, reason := range canceled.CancellationReasons {
+		switch aws.ToString(reason.Code) {
+		case "None", "TransactionConflict", "ThrottlingError", "ProvisionedThroughputExceeded":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func backoff(attempt int) time.Duration {
+	d := BatchWriteBaseBackoff << attempt
+	if d > BatchWriteMaxBackoff {
+		d = BatchWriteMaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1) //nolint:gosec // jitter does not need crypto rand
+}
+
+func sleepWithContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,11 +1,87 @@
 package handler
 
-import "time"
+import (
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/sketch"
+)
 
 const (
 	// Controller.
+	// The deadline budgets are taken from it, so it must stay below the function timeout of the stack.
 	ControllerTimeout = time.Second * 10
 
+	// Repository.
+	TableName             = "clickstream"
+	TransactWriteMaxItems = 100
+	TopClicksIndexName    = "TopClicksIndex"
+	SessionIndexName      = "SessionIndex"
+
+	// Retention.
+	DefaultRetention = time.Hour * 24 * 90
+
 	// ClickStream.
-	ClickEventCountLimit = 1000
+	ClickEventCountLimit        = 1000
+	DefaultClickStreamPageLimit = 100
+
+	// ClickCount.
+	DefaultClickCountMaxPages = 100
+	ClickCountDeadlineMargin  = time.Millisecond * 500
+
+	// Batch.
+	BatchMaxEvents        = 500
+	BatchWriteChunkSize   = 25
+	BatchWriteMaxAttempts = 5
+	BatchWriteBaseBackoff = time.Millisecond * 50
+	BatchWriteMaxBackoff  = time.Second
+
+	// ClickSeries.
+	DefaultHourSeriesSpan = time.Hour * 24
+	DefaultDaySeriesSpan  = time.Hour * 24 * 30
+	ClickSeriesMaxPoints  = 24 * 31
+
+	// Histogram.
+	DefaultMinuteHistogramSpan = time.Hour * 6
+	HistogramMaxBuckets        = 12 * 24 * 7
+	HistogramDeadlineMargin    = time.Millisecond * 500
+
+	// Sharding.
+	ShardConfigCacheTTL = time.Minute
+
+	// Sketches.
+	SketchMergeMaxAttempts = 5
+	UniqueSketchPrecision  = sketch.DefaultPrecision
+	DwellSketchCompression = sketch.DefaultCompression
+	DwellMaxGap            = time.Minute * 30
+
+	// TopPaths.
+	TopPathShards      = 8
+	DefaultTopPathsLen = 20
+
+	// Funnel.
+	FunnelMaxEventsPerStep = 20000
+
+	// Export.
+	ExportPageSize       = 500
+	ExportLambdaMaxRows  = 5000
+	ExportDeadlineMargin = time.Second
+	HeaderNextCursor     = "X-Next-Cursor"
+
+	// Live.
+	LiveSubscriberBuffer  = 64
+	LiveHeartbeatInterval = time.Second * 15
+
+	// Enrich.
+	DefaultEnrichStages       = "bot,useragent,geo,utm,fingerprint:closed:1s"
+	DefaultEnrichStageTimeout = time.Millisecond * 100
+
+	// Privacy.
+	FingerprintSaltPeriod = time.Hour * 24
+
+	// Erasure.
+	VisitorIndexName      = "VisitorIndex"
+	ErasureDeadlineMargin = time.Second
+	ErasurePassTimeout    = time.Second * 30
+	// IdempotencySubjectAttr puts idempotency records on the VisitorIndex, so they are erased with the events.
+	IdempotencySubjectAttr = "visitorId"
 )
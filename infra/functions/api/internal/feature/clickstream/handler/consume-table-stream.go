@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/ddbstream"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// primary adapter.
+// It receives the changes of the clickstream table and carries on the work that does not fit in a request,
+// such as the passes of a running erasure and the unique visitors and dwell times of new clicks.
+// The stream retries from the first failed record.
+func ConsumeTableStreamController(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "consumeTableStream",
+		"component", "controller",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ConsumeTableStreamController")
+	defer span.Close(nil)
+
+	var response events.DynamoDBEventResponse
+	fail := func(record events.DynamoDBEventRecord, err error) (events.DynamoDBEventResponse, error) {
+		commoninstrument.RecordError(logger, span, err)
+		// the records after a failed one are delivered again with it, so there is no point in going on.
+		response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+			ItemIdentifier: record.Change.SequenceNumber,
+		})
+		return response, nil
+	}
+
+	// new clicks are aggregated together, up to the next record of another kind.
+	clicks := make([]events.DynamoDBEventRecord, 0)
+	flush := func() error {
+		if len(clicks) == 0 {
+			return nil
+		}
+		err := consumeClickRecords(ctx, clicks)
+		if err == nil {
+			clicks = clicks[:0]
+		}
+		return err
+	}
+
+	for _, record := range event.Records {
+		if isClickRecord(record) {
+			clicks = append(clicks, record)
+			continue
+		}
+		if err := flush(); err != nil {
+			return fail(clicks[0], err)
+		}
+		if err := consumeTableRecord(ctx, record); err != nil {
+			return fail(record, err)
+		}
+	}
+	if err := flush(); err != nil {
+		return fail(clicks[0], err)
+	}
+
+	return response, nil
+}
+
+func isClickRecord(record events.DynamoDBEventRecord) bool {
+	if record.EventName != string(events.DynamoDBOperationTypeInsert) {
+		return false
+	}
+	pk, ok := record.Change.Keys["PK"]
+	return ok && pk.DataType() == events.DataTypeString && strings.HasPrefix(pk.String(), domain.EventPKPrefix)
+}
+
+func consumeClickRecords(ctx context.Context, records []events.DynamoDBEventRecord) error {
+	items := make([]map[string]types.AttributeValue, 0, len(records))
+	for _, record := range records {
+		item, err := ddbstream.Item(record.Change.NewImage)
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+
+	var clicks []domain.ClickEvent
+	if err := clickevent.UnmarshalItems(items, &clicks); err != nil {
+		return err
+	}
+
+	// merging a visitor into a sketch twice changes nothing, so the uniques go first and a failure
+	// retries the records; the dwell digests are not idempotent and never fail the records.
+	if err := AggregateUniquesService(ctx, clicks); err != nil {
+		return err
+	}
+	return AggregateDwellTimeService(ctx, clicks)
+}
+
+func consumeTableRecord(ctx context.Context, record events.DynamoDBEventRecord) error {
+	if record.EventName == string(events.DynamoDBOperationTypeRemove) {
+		return nil
+	}
+	pk, ok := record.Change.Keys["PK"]
+	if !ok || pk.DataType() != events.DataTypeString {
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(pk.String(), domain.ErasurePKPrefix):
+		item, err := ddbstream.Item(record.Change.NewImage)
+		if err != nil {
+			return err
+		}
+		var erasure domain.Erasure
+		if err := attributevalue.UnmarshalMap(item, &erasure); err != nil {
+			return err
+		}
+		_, err = ResumeErasureService(ctx, erasure)
+		return err
+	default:
+		return nil
+	}
+}
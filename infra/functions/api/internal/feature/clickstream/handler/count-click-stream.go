@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/cursor"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// primary adapter.
+func CountClickStreamController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "countClickStream",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "CountClickStreamController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.ClickCountQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	count, err := CountClickStreamService(ctx, path, &query)
+	if err != nil {
+		if errors.Is(err, cursor.ErrInvalidCursor) || errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to count clickstream for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": count.DTO(),
+	})
+}
+
+// service.
+func CountClickStreamService(ctx context.Context, path string, query *dto.ClickCountQuery) (domain.ClickCount, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "countClickStream",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "CountClickStreamService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var count domain.ClickCount
+
+	count, err := CountClickStreamRepository(ctx, path, query)
+	if err != nil {
+		instrument.RecordCountClickStreamError(logger, span, err)
+		return count, err
+	}
+
+	if !count.Exact {
+		instrument.RecordCountClickStreamTruncated(logger, count.ScannedPages)
+	}
+
+	return count, nil
+}
+
+// secondary adapter.
+func CountClickStreamRepository(ctx context.Context, path string, query *dto.ClickCountQuery) (domain.ClickCount, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "countClickStream",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "CountClickStreamRepository")
+	defer span.Close(nil)
+
+	var count domain.ClickCount
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return count, err
+	}
+
+	lo, hi, err := clickevent.SKRange(query.From, query.To)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return count, err
+	}
+
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return count, err
+	}
+
+	pk := domain.EventPK(path)
+	scope := fmt.Sprintf("count|%v|%v|%v", pk, lo, hi)
+
+	starts, err := decodeShardCursor(scope, query.Cursor, path, config.Shards)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return count, err
+	}
+
+	maxPages := query.MaxPages
+	if maxPages <= 0 {
+		maxPages = DefaultClickCountMaxPages
+	}
+
+	// the shards are counted concurrently, each within its own page budget.
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	shards := make([]int, 0, len(starts))
+	for shard := range starts {
+		shards = append(shards, shard)
+	}
+	errs := make([]error, len(shards))
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i, shard int) {
+			defer wg.Done()
+			shardCount, last, err := countShard(ctx, client, domain.EventShardPK(path, shard), lo, hi, query.IncludeBots, starts[shard], maxPages)
+
+			mu.Lock()
+			defer mu.Unlock()
+			errs[i] = err
+			count.Count += shardCount.Count
+			count.ScannedPages += shardCount.ScannedPages
+			if len(last) == 0 {
+				delete(starts, shard)
+			} else {
+				starts[shard] = last
+			}
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			return count, err
+		}
+	}
+
+	count.Exact = len(starts) == 0
+	count.NextCursor, err = encodeShardCursor(scope, config.Shards, starts)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return count, err
+	}
+
+	return count, nil
+}
+
+// countShard counts the events of one shard until it is exhausted or out of budget,
+// returning the key to resume from, which is empty once the shard is fully counted.
+func countShard(ctx context.Context, client *dynamodb.Client, pk, lo, hi string, includeBots bool, startKey map[string]types.AttributeValue, maxPages int) (domain.ClickCount, map[string]types.AttributeValue, error) {
+	var count domain.ClickCount
+
+	values := map[string]types.AttributeValue{
+		":pk": &types.AttributeValueMemberS{Value: pk},
+		":lo": &types.AttributeValueMemberS{Value: lo},
+		":hi": &types.AttributeValueMemberS{Value: hi},
+	}
+	params := &dynamodb.QueryInput{
+		TableName:                 aws.String(TableName),
+		KeyConditionExpression:    aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ExpressionAttributeValues: values,
+		FilterExpression:          clickevent.Filter("", includeBots, values),
+		Select:                    types.SelectCount,
+		ExclusiveStartKey:         startKey,
+	}
+	for {
+		output, err := client.Query(ctx, params)
+		if err != nil {
+			return count, params.ExclusiveStartKey, err
+		}
+
+		count.Count += int64(output.Count)
+		count.ScannedPages++
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return count, nil, nil
+		}
+		params.ExclusiveStartKey = output.LastEvaluatedKey
+
+		if count.ScannedPages >= maxPages || isDeadlineNear(ctx, ClickCountDeadlineMargin) {
+			return count, params.ExclusiveStartKey, nil
+		}
+	}
+}
+
+// isDeadlineNear reports whether the context ends within margin. On Lambda the request context
+// also carries the invocation deadline, so the earlier of the two counts.
+func isDeadlineNear(ctx context.Context, margin time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	return time.Until(deadline) < margin
+}
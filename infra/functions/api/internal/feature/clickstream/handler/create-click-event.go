@@ -2,20 +2,26 @@ package handler
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gin-gonic/gin"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
 	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/middleware"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
 	"github.com/oklog/ulid/v2"
 )
@@ -36,12 +42,45 @@ func CreateClickEventController(c *gin.Context) {
 
 	path := c.Param("path")
 
-	event, err := CreateClickEventService(ctx, &dto.ClickEvent{
-		ID:        ulid.Make().String(),
-		Path:      path,
-		CreatedAt: time.Now().UTC().Format(time.RFC3339),
-	})
+	var req dto.ClickEvent
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid body",
+		})
+		return
+	}
+	req.ID = ulid.Make().String()
+	req.Path = path
+	req.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	req.Client = &dto.ClientInfo{
+		IP:        middleware.ClientIP(c),
+		UserAgent: c.Request.UserAgent(),
+		Header:    c.Request.Header,
+	}
+
+	if err := util.ValidateStruct(&req); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	event, err := CreateClickEventService(ctx, &req)
 	if err != nil {
+		if errors.Is(err, enrich.ErrDropped) {
+			c.JSON(http.StatusAccepted, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, errTransactContended) {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"message": "path is busy, retry the click-event",
+			})
+			return
+		}
 		commoninstrument.RecordError(logger, span, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "failed to create click-event",
@@ -49,6 +88,7 @@ func CreateClickEventController(c *gin.Context) {
 		return
 	}
 
+	middleware.SetIdempotencySubject(c, event.VisitorID)
 	c.JSON(http.StatusOK, gin.H{
 		"data": event.DTO(),
 	})
@@ -67,13 +107,23 @@ func CreateClickEventService(ctx context.Context, req *dto.ClickEvent) (domain.C
 	commoninstrument.RecordRequest(logger, span, req)
 
 	var event domain.ClickEvent
+
+	if err := enrichClickEvents(ctx, req.Client, req); err != nil {
+		if errors.Is(err, enrich.ErrDropped) {
+			return event, err
+		}
+		instrument.RecordCreateClickEventError(logger, span, err)
+		return event, err
+	}
+
 	event, err := CreateClickEventRepository(ctx, req)
 	if err != nil {
-		instrument.RecordGetClickStreamError(logger, span, err)
+		instrument.RecordCreateClickEventError(logger, span, err)
 		return event, err
 	}
 
 	instrument.RecordCreateClickEventSuccess(logger)
+	publishLive(&event)
 
 	return event, nil
 }
@@ -97,27 +147,41 @@ func CreateClickEventRepository(ctx context.Context, req *dto.ClickEvent) (domai
 		return event, err
 	}
 
-	params := &dynamodb.PutItemInput{
-		TableName: aws.String("clickstream"),
-		Item: map[string]types.AttributeValue{
-			"PK":        &types.AttributeValueMemberS{Value: fmt.Sprintf("CLICK#EVENT#PATH#%v", req.Path)},
-			"SK":        &types.AttributeValueMemberS{Value: fmt.Sprintf("CLICK#EVENT#%v", req.ID)},
-			"id":        &types.AttributeValueMemberS{Value: req.ID},
-			"path":      &types.AttributeValueMemberS{Value: req.Path},
-			"createdAt": &types.AttributeValueMemberS{Value: req.CreatedAt},
-		},
-		ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+	newEvent := domain.NewClickEvent(req)
+	withRetention(&newEvent)
+	if err := withShard(ctx, client, &newEvent); err != nil {
+		commoninstrument.RecordError(logger, span, err)
 	}
-	_, err = client.PutItem(ctx, params)
+	item, err := attributevalue.MarshalMap(newEvent)
 	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return event, err
+	}
+
+	// the event and its counters are written together so they can never drift apart.
+	transactItems := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:           aws.String(TableName),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+			},
+		},
+	}
+	transactItems = append(transactItems, clickAggregateUpdates([]domain.ClickEvent{newEvent})...)
+
+	// the counters of a hot path are shared by concurrent clicks, so conflicts are retried.
+	params := &dynamodb.TransactWriteItemsInput{
+		TransactItems:      transactItems,
+		ClientRequestToken: aws.String(newEvent.ID),
+	}
+	if err := transactWriteWithRetry(ctx, client, params); err != nil {
 		commoninstrument.SetParamsToSpanAttr(logger, span, params)
 		commoninstrument.RecordError(logger, span, err)
 		return event, err
 	}
 
-	event.ID = req.ID
-	event.Path = req.Path
-	event.CreatedAt = req.CreatedAt
+	event = newEvent
 
 	return event, nil
 }
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/sketch"
+	"github.com/pkg/errors"
+)
+
+type dwellDelta struct {
+	Path   string
+	Period string
+	Digest *sketch.TDigest
+}
+
+// dwellSamples collects the time spent on a page, the time between a click and the next click
+// of the same session, into hour and day digests of the page the visitor stayed on.
+// The clicks of a session reach the stream on different shards and in any order,
+// so the previous click of every click is read from the SessionIndex instead of the batch.
+func dwellSamples(ctx context.Context, client *dynamodb.Client, events []domain.ClickEvent) ([]aggregateKey, map[aggregateKey]*dwellDelta, error) {
+	keys := make([]aggregateKey, 0)
+	deltas := make(map[aggregateKey]*dwellDelta)
+	add := func(prev *domain.ClickEvent, dwell time.Duration) error {
+		at, err := clickevent.ParseTimeBound(prev.ID)
+		if err != nil {
+			return nil
+		}
+		for _, granularity := range []string{domain.GranularityHour, domain.GranularityDay} {
+			period := domain.PeriodSK(granularity, at)
+			key := aggregateKey{PK: domain.DwellPK(prev.Path), SK: period}
+			delta, ok := deltas[key]
+			if !ok {
+				digest, err := sketch.NewTDigest(DwellSketchCompression)
+				if err != nil {
+					return err
+				}
+				delta = &dwellDelta{Path: prev.Path, Period: period, Digest: digest}
+				keys = append(keys, key)
+				deltas[key] = delta
+			}
+			delta.Digest.Add(float64(dwell.Milliseconds()))
+		}
+		return nil
+	}
+
+	for i := range events {
+		if events[i].IsBot || events[i].SessionID == "" {
+			continue
+		}
+		prev, err := previousSessionClick(ctx, client, events[i].SessionID, events[i].SK)
+		if err != nil {
+			return keys, deltas, err
+		}
+		// a bot click is not a page view, so it does not start a dwell.
+		if prev == nil || prev.IsBot {
+			continue
+		}
+		dwell, err := dwellBetween(prev, &events[i])
+		if err != nil || dwell <= 0 || dwell > DwellMaxGap {
+			continue
+		}
+		if err := add(prev, dwell); err != nil {
+			return keys, deltas, err
+		}
+	}
+
+	return keys, deltas, nil
+}
+
+// mergeDwellSamples folds the samples into the stored digests, one read-modify-write per digest.
+// A digest that can not be merged does not stop the others; its samples are dropped and counted,
+// since failing the stream batch would merge the other digests twice.
+func mergeDwellSamples(ctx context.Context, client *dynamodb.Client, keys []aggregateKey, deltas map[aggregateKey]*dwellDelta) (int, error) {
+	dropped := 0
+	var firstErr error
+	for _, key := range keys {
+		if err := mergeDwellSketch(ctx, client, key, deltas[key]); err != nil {
+			dropped += int(deltas[key].Digest.Count())
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "%v %v", key.PK, key.SK)
+			}
+		}
+	}
+	return dropped, firstErr
+}
+
+// previousSessionClick returns the latest click of the session before the given sort key, nil when there is none.
+func previousSessionClick(ctx context.Context, client *dynamodb.Client, sessionID, sk string) (*domain.ClickEvent, error) {
+	output, err := client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		IndexName:              aws.String(SessionIndexName),
+		KeyConditionExpression: aws.String("sessionId = :id AND SK < :sk"),
+		ProjectionExpression:   aws.String("id, #path, SK, isBot"),
+		ExpressionAttributeNames: map[string]string{
+			"#path": "path",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: sessionID},
+			":sk": &types.AttributeValueMemberS{Value: sk},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Items) == 0 {
+		return nil, nil
+	}
+
+	var prev domain.ClickEvent
+	if err := attributevalue.UnmarshalMap(output.Items[0], &prev); err != nil {
+		return nil, err
+	}
+	return &prev, nil
+}
+
+func dwellBetween(prev, next *domain.ClickEvent) (time.Duration, error) {
+	from, err := clickevent.ParseTimeBound(prev.ID)
+	if err != nil {
+		return 0, err
+	}
+	to, err := clickevent.ParseTimeBound(next.ID)
+	if err != nil {
+		return 0, err
+	}
+	return to.Sub(from), nil
+}
+
+func mergeDwellSketch(ctx context.Context, client *dynamodb.Client, key aggregateKey, delta *dwellDelta) error {
+	return mergeSketchItem(ctx, client, key, "tdigest", delta.Path, delta.Period, func(stored []byte) ([]byte, bool, error) {
+		merged, err := sketch.NewTDigest(DwellSketchCompression)
+		if err != nil {
+			return nil, false, err
+		}
+		if stored != nil {
+			if err := merged.UnmarshalBinary(stored); err != nil {
+				return nil, false, err
+			}
+		}
+
+		merged.Merge(delta.Digest)
+
+		data, err := merged.MarshalBinary()
+		return data, true, err
+	})
+}
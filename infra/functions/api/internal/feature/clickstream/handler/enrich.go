@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich/bot"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich/fingerprint"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich/geo"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich/useragent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/enrich/utm"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/privacy"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+var fingerprinter = privacy.NewFingerprinter(privacy.NewDynamoDBSaltStore(TableName), FingerprintSaltPeriod)
+
+// enrichers are the stages ENRICH_STAGES can list. A new enricher lives in its own package
+// under enrich and is registered here.
+var enrichers = map[string]enrich.Factory{
+	bot.Name:       bot.New,
+	useragent.Name: useragent.New,
+	geo.Name:       geo.New,
+	utm.Name:       utm.New,
+	fingerprint.Name: func() (enrich.Enricher, error) {
+		return fingerprint.New(fingerprinter)
+	},
+}
+
+var (
+	enrichOnce     sync.Once
+	enrichPipeline *enrich.Pipeline
+)
+
+// enrichClickEvents runs the enrichment pipeline over the events of one request,
+// then discards the raw IP, so it can not reach the store or the logs.
+// The stages come from ENRICH_STAGES, or DefaultEnrichStages when it is unset or invalid.
+func enrichClickEvents(ctx context.Context, client *dto.ClientInfo, reqs ...*dto.ClickEvent) error {
+	enrichOnce.Do(func() {
+		logger := slogger.New()
+
+		spec, ok := os.LookupEnv("ENRICH_STAGES")
+		if !ok {
+			spec = DefaultEnrichStages
+		}
+		stages, err := enrich.ParseStages(spec, enrichers, DefaultEnrichStageTimeout)
+		if err != nil {
+			logger.Error("ignore invalid enricher stages", "spec", spec, "err", err)
+			stages, _ = enrich.ParseStages(DefaultEnrichStages, enrichers, DefaultEnrichStageTimeout)
+		}
+		enrichPipeline = enrich.NewPipeline(stages...)
+		logger.Info("enrichment pipeline", "stages", enrichPipeline.Stages())
+	})
+
+	defer discardClientIP(client)
+
+	return enrichPipeline.Run(ctx, &enrich.Batch{Client: client, Events: reqs})
+}
+
+func discardClientIP(client *dto.ClientInfo) {
+	if client != nil {
+		client.IP = ""
+	}
+}
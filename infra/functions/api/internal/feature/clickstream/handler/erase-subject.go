@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/oklog/ulid/v2"
+)
+
+// primary adapter.
+func EraseSubjectController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "eraseSubject",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "EraseSubjectController")
+	defer span.Close(nil)
+
+	visitorID := c.Param("visitorId")
+	if visitorID == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "visitorId is required",
+		})
+		return
+	}
+
+	erasure, err := EraseSubjectService(ctx, visitorID)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to erase subject",
+		})
+		return
+	}
+
+	c.Header("Location", "/v1/clickstream/_erasures/"+erasure.JobID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": erasure.DTO(),
+	})
+}
+
+// service.
+// It only writes the erasure receipt. The new receipt reaches ConsumeTableStreamController,
+// which deletes the events in passes until none is left.
+func EraseSubjectService(ctx context.Context, visitorID string) (domain.Erasure, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "eraseSubject",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "EraseSubjectService")
+	defer span.Close(nil)
+
+	erasure := domain.NewErasure(ulid.Make().String(), visitorID, time.Now())
+	if err := PutErasureRepository(ctx, &erasure); err != nil {
+		instrument.RecordEraseSubjectError(logger, span, err)
+		return erasure, err
+	}
+
+	return erasure, nil
+}
+
+// service.
+// It runs one pass of a running erasure. Every pass updates the receipt, and that update
+// reaches the stream consumer again, so the passes go on until the job is completed.
+// A pass ends by ErasurePassTimeout or the invocation deadline of the worker, whichever comes first,
+// since the worker context carries the deadline.
+func ResumeErasureService(ctx context.Context, erasure domain.Erasure) (domain.Erasure, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "eraseSubject",
+		"component", "service",
+		"jobId", erasure.JobID,
+	)
+
+	ctx, cancel := context.WithTimeout(ctx, ErasurePassTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ResumeErasureService")
+	defer span.Close(nil)
+
+	if erasure.Status != domain.ErasureStatusRunning || erasure.SubjectID == "" {
+		return erasure, nil
+	}
+
+	if err := EraseSubjectRepository(ctx, &erasure); err != nil {
+		instrument.RecordEraseSubjectError(logger, span, err)
+		return erasure, err
+	}
+
+	if erasure.Status == domain.ErasureStatusCompleted {
+		instrument.RecordEraseSubjectCompleted(logger, erasure.Deleted)
+	}
+
+	return erasure, nil
+}
+
+// secondary adapter.
+func PutErasureRepository(ctx context.Context, erasure *domain.Erasure) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "eraseSubject",
+		"component", "repository",
+		"jobId", erasure.JobID,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "PutErasureRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	item, err := attributevalue.MarshalMap(erasure)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	params := &dynamodb.PutItemInput{
+		TableName:           aws.String(TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}
+	if _, err := client.PutItem(ctx, params); err != nil {
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	return nil
+}
+
+// secondary adapter.
+// It deletes the items of the subject found on the VisitorIndex, events and idempotency records alike,
+// until none is left or the deadline is near, and updates the receipt with the progress.
+// Within a pass the query resumes from its LastEvaluatedKey. Deleted items drop out of the index,
+// so the next pass queries from the beginning and only finds what is left.
+func EraseSubjectRepository(ctx context.Context, erasure *domain.Erasure) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "eraseSubject",
+		"component", "repository",
+		"jobId", erasure.JobID,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "EraseSubjectRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		IndexName:              aws.String(VisitorIndexName),
+		KeyConditionExpression: aws.String("visitorId = :visitorId"),
+		ProjectionExpression:   aws.String("PK, SK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":visitorId": &types.AttributeValueMemberS{Value: erasure.SubjectID},
+		},
+		Limit: aws.Int32(BatchWriteChunkSize),
+	}
+
+	var deleted int64
+	done := false
+	paginator := dynamodb.NewQueryPaginator(client, params)
+	for !isDeadlineNear(ctx, ErasureDeadlineMargin) {
+		if !paginator.HasMorePages() {
+			done = true
+			break
+		}
+
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			commoninstrument.SetParamsToSpanAttr(logger, span, params)
+			commoninstrument.RecordError(logger, span, err)
+			return err
+		}
+		if len(output.Items) == 0 {
+			continue
+		}
+
+		requests := make([]types.WriteRequest, 0, len(output.Items))
+		for _, item := range output.Items {
+			requests = append(requests, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{
+					Key: map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+				},
+			})
+		}
+		unprocessed, err := batchWriteWithRetry(ctx, client, requests)
+		deleted += int64(len(requests) - len(unprocessed))
+		if err != nil || len(unprocessed) > 0 {
+			// the next pass picks up what is left.
+			logger.Warn("erasure pass stopped early", "unprocessed", len(unprocessed), "err", err)
+			break
+		}
+	}
+
+	return updateErasure(ctx, client, erasure, deleted, done)
+}
+
+// updateErasure always counts the pass, so the receipt changes and the stream delivers it for the next pass.
+func updateErasure(ctx context.Context, client *dynamodb.Client, erasure *domain.Erasure, deleted int64, done bool) error {
+	update := "ADD deleted :deleted, passes :one"
+	values := map[string]types.AttributeValue{
+		":deleted": &types.AttributeValueMemberN{Value: strconv.FormatInt(deleted, 10)},
+		":one":     &types.AttributeValueMemberN{Value: "1"},
+	}
+	if done {
+		update += " SET #status = :completed, completedAt = :now REMOVE subjectId"
+		values[":completed"] = &types.AttributeValueMemberS{Value: domain.ErasureStatusCompleted}
+		values[":now"] = &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)}
+	}
+
+	params := &dynamodb.UpdateItemInput{
+		TableName: aws.String(TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: erasure.PK},
+			"SK": &types.AttributeValueMemberS{Value: erasure.SK},
+		},
+		UpdateExpression:          aws.String(update),
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	}
+	if done {
+		params.ExpressionAttributeNames = map[string]string{"#status": "status"}
+	}
+
+	output, err := client.UpdateItem(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	return attributevalue.UnmarshalMap(output.Attributes, erasure)
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/cursor"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// primary adapter.
+// Rows are written page by page as they are read. The local server flushes every page and
+// exports without limit. The Lambda proxy buffers the whole response, so there the export
+// stops at ExportLambdaMaxRows or near the deadline and the rest is resumed by X-Next-Cursor.
+func ExportClickStreamController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "exportClickStream",
+		"component", "controller",
+	)
+
+	local := util.IsLocalEnv()
+
+	ctx := c.Request.Context()
+	maxRows := 0
+	if !local {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ControllerTimeout)
+		defer cancel()
+		maxRows = ExportLambdaMaxRows
+	}
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ExportClickStreamController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.ExportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	rows := newRowWriter(query.Format, c.Writer)
+	started := false
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", rows.ContentType())
+		if local {
+			c.Header("Trailer", HeaderNextCursor)
+		}
+		c.Status(http.StatusOK)
+	}
+
+	emit := func(events []domain.ClickEvent) error {
+		start()
+		for i := range events {
+			event := events[i].DTO()
+			if err := rows.Write(&event); err != nil {
+				return err
+			}
+		}
+		if err := rows.Flush(); err != nil {
+			return err
+		}
+		if local {
+			c.Writer.Flush()
+		}
+		return nil
+	}
+
+	nextCursor, err := ExportClickStreamService(ctx, path, &query, maxRows, emit)
+	if err != nil {
+		if started {
+			// the status is already sent, so the client sees a truncated body.
+			commoninstrument.RecordError(logger, span, err)
+			return
+		}
+		if errors.Is(err, cursor.ErrInvalidCursor) || errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to export clickstream for path",
+		})
+		return
+	}
+
+	start()
+	if err := rows.Flush(); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+	}
+	// sent as a trailer by net/http, and as a header by the Lambda proxy that buffers the body.
+	c.Writer.Header().Set(HeaderNextCursor, nextCursor)
+}
+
+// service.
+func ExportClickStreamService(ctx context.Context, path string, query *dto.ExportQuery, maxRows int, emit func([]domain.ClickEvent) error) (string, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "exportClickStream",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ExportClickStreamService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	rows, nextCursor, err := ExportClickStreamRepository(ctx, path, query, maxRows, emit)
+	if err != nil {
+		instrument.RecordExportClickStreamError(logger, span, err)
+		return nextCursor, err
+	}
+
+	instrument.RecordExportClickStreamRows(logger, rows, nextCursor != "")
+
+	return nextCursor, nil
+}
+
+// secondary adapter.
+// It emits every page as it is read and returns the number of rows with the cursor to resume from.
+// A zero maxRows exports the whole range.
+func ExportClickStreamRepository(ctx context.Context, path string, query *dto.ExportQuery, maxRows int, emit func([]domain.ClickEvent) error) (int, string, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "exportClickStream",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ExportClickStreamRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, "", err
+	}
+
+	lo, hi, err := clickevent.SKRange(query.From, query.To)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, "", err
+	}
+
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, "", err
+	}
+
+	pk := domain.EventPK(path)
+	scope := fmt.Sprintf("export|%v|%v|%v", pk, lo, hi)
+
+	starts, err := decodeShardCursor(scope, query.Cursor, path, config.Shards)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return 0, "", err
+	}
+
+	events := &shardedEventQuery{
+		client:      client,
+		path:        path,
+		lo:          lo,
+		hi:          hi,
+		includeBots: query.IncludeBots,
+		starts:      starts,
+	}
+
+	rows := 0
+	for !events.done() {
+		limit := int32(ExportPageSize)
+		if maxRows > 0 && maxRows-rows < ExportPageSize {
+			limit = int32(maxRows - rows)
+		}
+
+		page, err := events.next(ctx, limit)
+		if err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			return rows, "", err
+		}
+		if len(page) > 0 {
+			if err := emit(page); err != nil {
+				commoninstrument.RecordError(logger, span, err)
+				return rows, "", err
+			}
+			rows += len(page)
+		}
+
+		if (maxRows > 0 && rows >= maxRows) || isDeadlineNear(ctx, ExportDeadlineMargin) {
+			break
+		}
+	}
+
+	nextCursor, err := encodeShardCursor(scope, config.Shards, events.starts)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return rows, "", err
+	}
+
+	return rows, nextCursor, nil
+}
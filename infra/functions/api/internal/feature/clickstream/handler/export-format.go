@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+)
+
+const (
+	ExportFormatNDJSON = "ndjson"
+	ExportFormatCSV    = "csv"
+)
+
+// exportColumns are derived from the json fields of dto.ClickEvent, which are also what NDJSON writes,
+// so a field added to the event shows up in both formats. Nested objects are flattened, e.g. viewportWidth.
+var exportColumns = columnsOf(reflect.TypeOf(dto.ClickEvent{}), "", nil)
+
+type exportColumn struct {
+	name  string
+	index []int
+}
+
+func columnsOf(t reflect.Type, prefix string, index []int) []exportColumn {
+	columns := make([]exportColumn, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + strings.ToUpper(name[:1]) + name[1:]
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+
+		typ := field.Type
+		if typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ.Kind() == reflect.Struct {
+			columns = append(columns, columnsOf(typ, name, fieldIndex)...)
+			continue
+		}
+		columns = append(columns, exportColumn{name: name, index: fieldIndex})
+	}
+	return columns
+}
+
+// cell returns the CSV cell of the column, empty for a missing value.
+func (c exportColumn) cell(event *dto.ClickEvent) (string, error) {
+	v := reflect.ValueOf(event).Elem()
+	for _, i := range c.index {
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return "", nil
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return escapeCSVFormula(v.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Map, reflect.Slice:
+		if v.Len() == 0 {
+			return "", nil
+		}
+		b, err := json.Marshal(v.Interface())
+		if err != nil {
+			return "", err
+		}
+		return escapeCSVFormula(string(b)), nil
+	default:
+		return escapeCSVFormula(fmt.Sprint(v.Interface())), nil
+	}
+}
+
+// escapeCSVFormula keeps spreadsheets from evaluating a client supplied value as a formula.
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// rowWriter encodes exported events one row at a time.
+type rowWriter interface {
+	ContentType() string
+	Write(event *dto.ClickEvent) error
+	Flush() error
+}
+
+func newRowWriter(format string, w io.Writer) rowWriter {
+	if format == ExportFormatCSV {
+		return &csvRowWriter{w: csv.NewWriter(w)}
+	}
+	return &ndjsonRowWriter{enc: json.NewEncoder(w)}
+}
+
+type ndjsonRowWriter struct {
+	enc *json.Encoder
+}
+
+func (w *ndjsonRowWriter) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (w *ndjsonRowWriter) Write(event *dto.ClickEvent) error {
+	return w.enc.Encode(event)
+}
+
+func (w *ndjsonRowWriter) Flush() error {
+	return nil
+}
+
+type csvRowWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (w *csvRowWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (w *csvRowWriter) Write(event *dto.ClickEvent) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	row := make([]string, 0, len(exportColumns))
+	for _, column := range exportColumns {
+		cell, err := column.cell(event)
+		if err != nil {
+			return err
+		}
+		row = append(row, cell)
+	}
+	return w.w.Write(row)
+}
+
+// Flush also writes the header, so an empty export is still a valid CSV.
+func (w *csvRowWriter) Flush() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func (w *csvRowWriter) writeHeader() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+
+	header := make([]string, 0, len(exportColumns))
+	for _, column := range exportColumns {
+		header = append(header, column.name)
+	}
+	return w.w.Write(header)
+}
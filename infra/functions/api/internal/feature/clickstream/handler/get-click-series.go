@@ -0,0 +1,207 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetClickSeriesController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getClickSeries",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetClickSeriesController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.ClickSeriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	series, err := GetClickSeriesService(ctx, path, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get click series for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": series.DTO(),
+	})
+}
+
+// service.
+func GetClickSeriesService(ctx context.Context, path string, query *dto.ClickSeriesQuery) (domain.ClickSeries, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getClickSeries",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetClickSeriesService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	series := domain.ClickSeries{Granularity: query.Granularity}
+	if series.Granularity == "" {
+		series.Granularity = domain.GranularityHour
+	}
+
+	from, to, err := seriesRange(series.Granularity, query.From, query.To)
+	if err != nil {
+		instrument.RecordGetClickSeriesError(logger, span, err)
+		return series, err
+	}
+
+	counters, err := GetClickSeriesRepository(ctx, path, series.Granularity, from, to)
+	if err != nil {
+		instrument.RecordGetClickSeriesError(logger, span, err)
+		return series, err
+	}
+
+	// a sharded path has a counter per shard in every bucket.
+	counts := make(map[string]int64, len(counters))
+	for _, counter := range counters {
+		counts[counter.SK] += counter.Count
+	}
+
+	// fill the buckets without a counter item with zero.
+	for t := from; !t.After(to); t = domain.NextPeriod(series.Granularity, t) {
+		series.Points = append(series.Points, domain.ClickSeriesPoint{
+			Time:  t,
+			Count: counts[domain.PeriodSK(series.Granularity, t)],
+		})
+	}
+
+	return series, nil
+}
+
+// secondary adapter.
+func GetClickSeriesRepository(ctx context.Context, path, granularity string, from, to time.Time) ([]domain.ClickCounter, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getClickSeries",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetClickSeriesRepository")
+	defer span.Close(nil)
+
+	var counters []domain.ClickCounter
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: domain.CounterPK(path)},
+			":lo": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, from)},
+			":hi": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, to)},
+		},
+	}
+	items, err := queryAggregateShards(ctx, client, path, domain.CounterPK(path), params)
+	if err != nil {
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+	if err := attributevalue.UnmarshalListOfMaps(items, &counters); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+
+	return counters, nil
+}
+
+// seriesRange resolves the optional from/to bounds into bucket starts, defaulting to the latest day or month.
+func seriesRange(granularity, fromValue, toValue string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toValue != "" {
+		t, err := clickevent.ParseTimeBound(toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	window := DefaultHourSeriesSpan
+	if granularity == domain.GranularityDay {
+		window = DefaultDaySeriesSpan
+	}
+	from := to.Add(-window)
+	if fromValue != "" {
+		t, err := clickevent.ParseTimeBound(fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	from = domain.TruncatePeriod(granularity, from)
+	to = domain.TruncatePeriod(granularity, to)
+	if from.After(to) {
+		return from, to, errors.Wrap(domain.ErrInvalidTimeRange, "from is later than to")
+	}
+
+	points := 0
+	for t := from; !t.After(to); t = domain.NextPeriod(granularity, t) {
+		points++
+		if points > ClickSeriesMaxPoints {
+			return from, to, errors.Wrapf(domain.ErrInvalidTimeRange, "range exceeds %v points", ClickSeriesMaxPoints)
+		}
+	}
+
+	return from, to, nil
+}
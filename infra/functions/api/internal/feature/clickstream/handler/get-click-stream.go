@@ -2,19 +2,20 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
-	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
 	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/cursor"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
 	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
 )
 
@@ -34,8 +35,31 @@ func GetClickStreamController(c *gin.Context) {
 
 	path := c.Param("path")
 
-	result, err := GetClickStreamService(ctx, path)
+	var query dto.ClickStreamQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	page, err := GetClickStreamService(ctx, path, &query)
 	if err != nil {
+		if errors.Is(err, cursor.ErrInvalidCursor) || errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
 		commoninstrument.RecordError(logger, span, err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
 			"message": "failed to get clickstream for path",
@@ -44,12 +68,13 @@ func GetClickStreamController(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": len(result),
+		"data":       page.DTO(),
+		"nextCursor": page.NextCursor,
 	})
 }
 
 // service.
-func GetClickStreamService(ctx context.Context, path string) ([]domain.ClickEvent, error) {
+func GetClickStreamService(ctx context.Context, path string, query *dto.ClickStreamQuery) (domain.ClickStreamPage, error) {
 	logger := slogger.New().WithContext(ctx).WithArgs(
 		"feature", "clickstream",
 		"usecase", "getClickStream",
@@ -60,19 +85,21 @@ func GetClickStreamService(ctx context.Context, path string) ([]domain.ClickEven
 	ctx, span := o11y.BeginSubSegment(ctx, "GetClickStreamService")
 	defer span.Close(nil)
 
-	var clickstream []domain.ClickEvent
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var page domain.ClickStreamPage
 
-	clickstream, err := GetClickStreamRepository(ctx, path)
+	page, err := GetClickStreamRepository(ctx, path, query)
 	if err != nil {
 		instrument.RecordGetClickStreamError(logger, span, err)
-		return clickstream, err
+		return page, err
 	}
 
-	return clickstream, nil
+	return page, nil
 }
 
 // secondary adapter.
-func GetClickStreamRepository(ctx context.Context, path string) ([]domain.ClickEvent, error) {
+func GetClickStreamRepository(ctx context.Context, path string, query *dto.ClickStreamQuery) (domain.ClickStreamPage, error) {
 	logger := slogger.New().WithContext(ctx).WithArgs(
 		"feature", "clickstream",
 		"usecase", "getClickStream",
@@ -83,36 +110,62 @@ func GetClickStreamRepository(ctx context.Context, path string) ([]domain.ClickE
 	ctx, span := o11y.BeginSubSegment(ctx, "GetClickStreamRepository")
 	defer span.Close(nil)
 
-	var clickstream []domain.ClickEvent
+	var page domain.ClickStreamPage
 
 	client, err := cloud.NewDynamoDBClient()
 	if err != nil {
 		commoninstrument.RecordError(logger, span, err)
-		return clickstream, err
+		return page, err
+	}
+
+	lo, hi, err := clickevent.SKRange(query.From, query.To)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return page, err
 	}
 
-	params := &dynamodb.QueryInput{
-		TableName:              aws.String("clickstream"),
-		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{Value: fmt.Sprintf("CLICK#EVENT#PATH#%v", path)},
-			":sk": &types.AttributeValueMemberS{Value: "CLICK#EVENT#"},
-		},
-		ProjectionExpression: aws.String("ID"),
-		Limit:                aws.Int32(ClickEventCountLimit),
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return page, err
+	}
+
+	pk := domain.EventPK(path)
+	scope := fmt.Sprintf("%v|%v|%v", pk, lo, hi)
+
+	starts, err := decodeShardCursor(scope, query.Cursor, path, config.Shards)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return page, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultClickStreamPageLimit
+	}
+	if limit > ClickEventCountLimit {
+		limit = ClickEventCountLimit
 	}
-	output, err := client.Query(ctx, params)
 
+	events := &shardedEventQuery{
+		client:      client,
+		path:        path,
+		lo:          lo,
+		hi:          hi,
+		includeBots: query.IncludeBots,
+		starts:      starts,
+	}
+	page.Items, err = events.next(ctx, limit)
 	if err != nil {
-		commoninstrument.SetParamsToSpanAttr(logger, span, params)
 		commoninstrument.RecordError(logger, span, err)
-		return clickstream, err
+		return page, err
 	}
 
-	if err := attributevalue.UnmarshalListOfMaps(output.Items, &clickstream); err != nil {
+	page.NextCursor, err = encodeShardCursor(scope, config.Shards, events.starts)
+	if err != nil {
 		commoninstrument.RecordError(logger, span, err)
-		return clickstream, err
+		return page, err
 	}
 
-	return clickstream, nil
+	return page, nil
 }
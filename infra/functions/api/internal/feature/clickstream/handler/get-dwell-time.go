@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/sketch"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetDwellTimeController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getDwellTime",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetDwellTimeController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.DwellTimeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	dwell, err := GetDwellTimeService(ctx, path, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get dwell time for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": dwell.DTO(),
+	})
+}
+
+// service.
+// The digests of the periods in range are merged before the percentiles are read.
+func GetDwellTimeService(ctx context.Context, path string, query *dto.DwellTimeQuery) (domain.DwellTime, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getDwellTime",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetDwellTimeService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	dwell := domain.DwellTime{Path: path, Granularity: query.Granularity}
+	if dwell.Granularity == "" {
+		dwell.Granularity = domain.GranularityHour
+	}
+
+	from, to, err := seriesRange(dwell.Granularity, query.From, query.To)
+	if err != nil {
+		instrument.RecordGetDwellTimeError(logger, span, err)
+		return dwell, err
+	}
+	dwell.From, dwell.To = from, to
+
+	sketches, err := GetDwellTimeRepository(ctx, path, dwell.Granularity, from, to)
+	if err != nil {
+		instrument.RecordGetDwellTimeError(logger, span, err)
+		return dwell, err
+	}
+
+	merged, err := sketch.NewTDigest(DwellSketchCompression)
+	if err != nil {
+		instrument.RecordGetDwellTimeError(logger, span, err)
+		return dwell, err
+	}
+	for _, item := range sketches {
+		var digest sketch.TDigest
+		if err := digest.UnmarshalBinary(item.Sketch); err != nil {
+			instrument.RecordGetDwellTimeError(logger, span, err)
+			return dwell, err
+		}
+		merged.Merge(&digest)
+	}
+
+	dwell.Periods = len(sketches)
+	dwell.Count = int64(merged.Count())
+	if dwell.Count > 0 {
+		dwell.P50 = time.Duration(merged.Quantile(0.5)) * time.Millisecond
+		dwell.P90 = time.Duration(merged.Quantile(0.9)) * time.Millisecond
+		dwell.P99 = time.Duration(merged.Quantile(0.99)) * time.Millisecond
+	}
+
+	return dwell, nil
+}
+
+// secondary adapter.
+func GetDwellTimeRepository(ctx context.Context, path, granularity string, from, to time.Time) ([]domain.DwellSketch, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getDwellTime",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetDwellTimeRepository")
+	defer span.Close(nil)
+
+	var sketches []domain.DwellSketch
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return sketches, err
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: domain.DwellPK(path)},
+			":lo": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, from)},
+			":hi": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, to)},
+		},
+	}
+	paginator := dynamodb.NewQueryPaginator(client, params)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			commoninstrument.SetParamsToSpanAttr(logger, span, params)
+			commoninstrument.RecordError(logger, span, err)
+			return sketches, err
+		}
+
+		var page []domain.DwellSketch
+		if err := attributevalue.UnmarshalListOfMaps(output.Items, &page); err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			return sketches, err
+		}
+		sketches = append(sketches, page...)
+	}
+
+	return sketches, nil
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetErasureController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getErasure",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetErasureController")
+	defer span.Close(nil)
+
+	jobID := c.Param("jobId")
+
+	erasure, err := GetErasureService(ctx, jobID)
+	if err != nil {
+		if errors.Is(err, domain.ErrErasureNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "erasure not found",
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get erasure",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": erasure.DTO(),
+	})
+}
+
+// service.
+func GetErasureService(ctx context.Context, jobID string) (domain.Erasure, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getErasure",
+		"component", "service",
+		"jobId", jobID,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetErasureService")
+	defer span.Close(nil)
+
+	erasure, err := GetErasureRepository(ctx, jobID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrErasureNotFound) {
+			instrument.RecordGetErasureError(logger, span, err)
+		}
+		return erasure, err
+	}
+
+	return erasure, nil
+}
+
+// secondary adapter.
+func GetErasureRepository(ctx context.Context, jobID string) (domain.Erasure, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getErasure",
+		"component", "repository",
+		"jobId", jobID,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetErasureRepository")
+	defer span.Close(nil)
+
+	var erasure domain.Erasure
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return erasure, err
+	}
+
+	params := &dynamodb.GetItemInput{
+		TableName: aws.String(TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: domain.ErasurePK(jobID)},
+			"SK": &types.AttributeValueMemberS{Value: domain.ErasureSK},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+	output, err := client.GetItem(ctx, params)
+	if err != nil {
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return erasure, err
+	}
+	if len(output.Item) == 0 {
+		return erasure, domain.ErrErasureNotFound
+	}
+
+	if err := attributevalue.UnmarshalMap(output.Item, &erasure); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return erasure, err
+	}
+
+	return erasure, nil
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidWindow = errors.New("invalid window")
+
+// primary adapter.
+func GetFunnelController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getFunnel",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetFunnelController")
+	defer span.Close(nil)
+
+	var query dto.FunnelQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	funnel, err := GetFunnelService(ctx, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) || errors.Is(err, ErrInvalidWindow) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get funnel",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": funnel.DTO(),
+	})
+}
+
+// service.
+func GetFunnelService(ctx context.Context, query *dto.FunnelQuery) (domain.Funnel, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getFunnel",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetFunnelService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var funnel domain.Funnel
+
+	var window time.Duration
+	if query.Window != "" {
+		w, err := time.ParseDuration(query.Window)
+		if err != nil || w <= 0 {
+			err = errors.Wrapf(ErrInvalidWindow, "%v is not a positive duration", query.Window)
+			instrument.RecordGetFunnelError(logger, span, err)
+			return funnel, err
+		}
+		window = w
+	}
+
+	lo, hi, err := clickevent.SKRange(query.From, query.To)
+	if err != nil {
+		instrument.RecordGetFunnelError(logger, span, err)
+		return funnel, err
+	}
+
+	steps := make([]map[string][]time.Time, 0, len(query.Steps))
+	for _, path := range query.Steps {
+		visits, truncated, err := GetFunnelRepository(ctx, path, lo, hi, query.IncludeBots)
+		if err != nil {
+			instrument.RecordGetFunnelError(logger, span, err)
+			return funnel, err
+		}
+		funnel.Truncated = funnel.Truncated || truncated
+		steps = append(steps, visits)
+	}
+
+	funnel.Steps = computeFunnel(ctx, query.Steps, steps, window)
+
+	return funnel, nil
+}
+
+// computeFunnel walks the steps in order. A visitor reaches a step with its first visit
+// at or after the visit that reached the previous step, and within the window from the first step.
+// Every visit of the first step starts a new attempt, and a visitor counts with its furthest one.
+func computeFunnel(ctx context.Context, paths []string, steps []map[string][]time.Time, window time.Duration) []domain.FunnelStep {
+	_, span := o11y.BeginSubSegment(ctx, "ComputeFunnel")
+	defer span.Close(nil)
+
+	result := make([]domain.FunnelStep, len(paths))
+	for i := range paths {
+		result[i].Path = paths[i]
+	}
+
+	for visitor, starts := range steps[0] {
+		best := 0
+		for _, start := range starts {
+			best = max(best, funnelProgress(steps, visitor, start, window))
+			if best == len(steps)-1 {
+				break
+			}
+		}
+		for i := 0; i <= best; i++ {
+			result[i].Visitors++
+		}
+	}
+
+	if span != nil {
+		_ = span.AddMetadata("steps", result)
+	}
+
+	return result
+}
+
+// funnelProgress returns the last step the visitor reaches in the attempt that starts at start.
+// Taking the earliest qualifying visit of every step leaves the most room for the next ones.
+func funnelProgress(steps []map[string][]time.Time, visitor string, start time.Time, window time.Duration) int {
+	prev := start
+	for i := 1; i < len(steps); i++ {
+		visits := steps[i][visitor]
+		j := sort.Search(len(visits), func(k int) bool {
+			return !visits[k].Before(prev)
+		})
+		if j == len(visits) {
+			return i - 1
+		}
+		if window > 0 && visits[j].Sub(start) > window {
+			return i - 1
+		}
+		prev = visits[j]
+	}
+	return len(steps) - 1
+}
+
+// secondary adapter.
+// It returns the ordered visit times of every visitor on the path.
+func GetFunnelRepository(ctx context.Context, path, lo, hi string, includeBots bool) (map[string][]time.Time, bool, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getFunnel",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetFunnelRepository")
+	defer span.Close(nil)
+
+	visits := make(map[string][]time.Time)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return visits, false, err
+	}
+
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return visits, false, err
+	}
+
+	scanned := 0
+	for shard := 0; shard < config.Shards; shard++ {
+		values := map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: domain.EventShardPK(path, shard)},
+			":lo": &types.AttributeValueMemberS{Value: lo},
+			":hi": &types.AttributeValueMemberS{Value: hi},
+		}
+		params := &dynamodb.QueryInput{
+			TableName:                 aws.String(TableName),
+			KeyConditionExpression:    aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+			FilterExpression:          clickevent.Filter("attribute_exists(visitorId)", includeBots, values),
+			ProjectionExpression:      aws.String("id, visitorId, schemaVersion"),
+			ExpressionAttributeValues: values,
+		}
+
+		paginator := dynamodb.NewQueryPaginator(client, params)
+		for paginator.HasMorePages() {
+			if scanned >= FunnelMaxEventsPerStep {
+				return visits, true, nil
+			}
+
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				commoninstrument.SetParamsToSpanAttr(logger, span, params)
+				commoninstrument.RecordError(logger, span, err)
+				return visits, false, err
+			}
+
+			var page []domain.ClickEvent
+			if err := clickevent.UnmarshalItems(output.Items, &page); err != nil {
+				commoninstrument.RecordError(logger, span, err)
+				return visits, false, err
+			}
+
+			for i := range page {
+				id, err := ulid.ParseStrict(page[i].ID)
+				if err != nil {
+					continue
+				}
+				visits[page[i].VisitorID] = append(visits[page[i].VisitorID], ulid.Time(id.Time()))
+			}
+			scanned += len(page)
+		}
+	}
+
+	// every shard is in SK order on its own, so the visits of a visitor are sorted once all shards are read.
+	if config.Shards > 1 {
+		for _, times := range visits {
+			sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		}
+	}
+
+	return visits, false, nil
+}
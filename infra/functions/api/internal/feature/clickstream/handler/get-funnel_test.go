@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestComputeFunnel(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes ...int) []time.Time {
+		visits := make([]time.Time, 0, len(minutes))
+		for _, m := range minutes {
+			visits = append(visits, base.Add(time.Duration(m)*time.Minute))
+		}
+		return visits
+	}
+	paths := []string{"/home", "/cart", "/checkout"}
+
+	tests := []struct {
+		name   string
+		steps  []map[string][]time.Time
+		window time.Duration
+		want   []int
+	}{
+		{
+			name: "in order",
+			steps: []map[string][]time.Time{
+				{"a": at(0), "b": at(0)},
+				{"a": at(1), "b": at(1)},
+				{"a": at(2)},
+			},
+			want: []int{2, 2, 1},
+		},
+		{
+			name: "out of order",
+			steps: []map[string][]time.Time{
+				{"a": at(5)},
+				{"a": at(1)},
+				{"a": at(6)},
+			},
+			want: []int{1, 0, 0},
+		},
+		{
+			name: "outside the window",
+			steps: []map[string][]time.Time{
+				{"a": at(0)},
+				{"a": at(10)},
+				{"a": at(90)},
+			},
+			window: time.Hour,
+			want:   []int{1, 1, 0},
+		},
+		{
+			name: "return visit completes within the window",
+			steps: []map[string][]time.Time{
+				{"a": at(0, 120)},
+				{"a": at(125)},
+				{"a": at(130)},
+			},
+			window: time.Hour,
+			want:   []int{1, 1, 1},
+		},
+		{
+			name: "furthest attempt counts",
+			steps: []map[string][]time.Time{
+				{"a": at(0, 120)},
+				{"a": at(10, 125)},
+				{"a": at(30)},
+			},
+			window: time.Hour,
+			want:   []int{1, 1, 1},
+		},
+		{
+			name: "visitors without the first step are not counted",
+			steps: []map[string][]time.Time{
+				{"a": at(0)},
+				{"a": at(1), "b": at(1)},
+				{"b": at(2)},
+			},
+			want: []int{1, 1, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeFunnel(context.Background(), paths, tt.steps, tt.window)
+			if len(got) != len(paths) {
+				t.Fatalf("got %d steps, want %d", len(got), len(paths))
+			}
+			for i, step := range got {
+				if step.Path != paths[i] || step.Visitors != tt.want[i] {
+					t.Errorf("step %d = %v %d, want %v %d", i, step.Path, step.Visitors, paths[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+// primary adapter.
+func GetHeatmapController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getHeatmap",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetHeatmapController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.HeatmapQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	heatmap, err := GetHeatmapService(ctx, path, &query)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get heatmap for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": heatmap.DTO(),
+	})
+}
+
+// service.
+func GetHeatmapService(ctx context.Context, path string, query *dto.HeatmapQuery) (domain.Heatmap, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getHeatmap",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetHeatmapService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	bucket := query.Bucket
+	if bucket == "" {
+		bucket = domain.BucketDesktop
+	}
+
+	heatmap := domain.NewHeatmap(path, bucket)
+
+	counters, err := GetHeatmapRepository(ctx, path, bucket)
+	if err != nil {
+		instrument.RecordGetHeatmapError(logger, span, err)
+		return heatmap, err
+	}
+
+	for _, counter := range counters {
+		row, col, ok := domain.ParseHeatSK(counter.SK)
+		if !ok {
+			continue
+		}
+		heatmap.Add(row, col, counter.Count)
+	}
+
+	return heatmap, nil
+}
+
+// secondary adapter.
+func GetHeatmapRepository(ctx context.Context, path, bucket string) ([]domain.HeatmapCounter, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getHeatmap",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetHeatmapRepository")
+	defer span.Close(nil)
+
+	var counters []domain.HeatmapCounter
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		KeyConditionExpression: aws.String("PK = :pk"),
+		ProjectionExpression:   aws.String("PK, SK, #count"),
+		ExpressionAttributeNames: map[string]string{
+			"#count": "count",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: domain.HeatPK(bucket, path)},
+		},
+	}
+	items, err := queryAggregateShards(ctx, client, path, domain.HeatPK(bucket, path), params)
+	if err != nil {
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+	if err := attributevalue.UnmarshalListOfMaps(items, &counters); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return counters, err
+	}
+
+	return counters, nil
+}
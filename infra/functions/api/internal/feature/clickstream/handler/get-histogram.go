@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetHistogramController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getHistogram",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetHistogramController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.HistogramQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	histogram, err := GetHistogramService(ctx, path, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) || errors.Is(err, domain.ErrInvalidTimeZone) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get histogram for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": histogram.DTO(),
+	})
+}
+
+// service.
+// Hour counters answer hour and day buckets that start on UTC hours,
+// other buckets and bot-inclusive reads are counted from the events themselves.
+func GetHistogramService(ctx context.Context, path string, query *dto.HistogramQuery) (domain.Histogram, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getHistogram",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetHistogramService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var histogram domain.Histogram
+
+	interval := query.Interval
+	if interval == "" {
+		interval = domain.Interval1h
+	}
+
+	loc, err := domain.LoadTimeZone(query.TZ)
+	if err != nil {
+		instrument.RecordGetHistogramError(logger, span, err)
+		return histogram, err
+	}
+
+	from, to, err := histogramRange(interval, query.From, query.To)
+	if err != nil {
+		instrument.RecordGetHistogramError(logger, span, err)
+		return histogram, err
+	}
+
+	histogram = domain.NewHistogram(path, interval, loc, from, to)
+	start := histogram.Buckets[0].Start
+	end := histogram.Buckets[len(histogram.Buckets)-1].End
+
+	if interval != domain.Interval5m && !query.IncludeBots && histogram.HourAligned() {
+		histogram.Source = domain.HistogramSourceCounters
+
+		counters, err := GetClickSeriesRepository(ctx, path, domain.GranularityHour, start, end.Add(-time.Hour))
+		if err != nil {
+			instrument.RecordGetHistogramError(logger, span, err)
+			return histogram, err
+		}
+
+		counts := make(map[string]int64, len(counters))
+		for _, counter := range counters {
+			counts[counter.SK] = counter.Count
+		}
+		for t := start; t.Before(end); t = t.Add(time.Hour) {
+			histogram.Add(t, counts[domain.PeriodSK(domain.GranularityHour, t)])
+		}
+
+		return histogram, nil
+	}
+
+	histogram.Source = domain.HistogramSourceEvents
+
+	pageQuery := &dto.ClickStreamQuery{
+		PageQuery:   dto.PageQuery{Limit: ClickEventCountLimit},
+		From:        start.UTC().Format(time.RFC3339Nano),
+		To:          end.Add(-time.Millisecond).UTC().Format(time.RFC3339Nano),
+		IncludeBots: query.IncludeBots,
+	}
+	for pages := 1; ; pages++ {
+		page, err := GetClickStreamRepository(ctx, path, pageQuery)
+		if err != nil {
+			instrument.RecordGetHistogramError(logger, span, err)
+			return histogram, err
+		}
+
+		for i := range page.Items {
+			t, err := clickevent.ParseTimeBound(page.Items[i].ID)
+			if err != nil {
+				continue
+			}
+			histogram.Add(t, 1)
+		}
+
+		if page.NextCursor == "" {
+			return histogram, nil
+		}
+		if isDeadlineNear(ctx, HistogramDeadlineMargin) {
+			histogram.Exact = false
+			instrument.RecordGetHistogramTruncated(logger, pages)
+			return histogram, nil
+		}
+		pageQuery.Cursor = page.NextCursor
+	}
+}
+
+// histogramRange resolves the optional from/to bounds, defaulting to the latest six hours, day or month.
+func histogramRange(interval, fromValue, toValue string) (time.Time, time.Time, error) {
+	to := time.Now()
+	if toValue != "" {
+		t, err := clickevent.ParseTimeBound(toValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	window := DefaultHourSeriesSpan
+	switch interval {
+	case domain.Interval5m:
+		window = DefaultMinuteHistogramSpan
+	case domain.Interval1d:
+		window = DefaultDaySeriesSpan
+	}
+	from := to.Add(-window)
+	if fromValue != "" {
+		t, err := clickevent.ParseTimeBound(fromValue)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		return from, to, errors.Wrap(domain.ErrInvalidTimeRange, "from is later than to")
+	}
+	if to.Sub(from)/domain.IntervalDuration(interval) >= HistogramMaxBuckets {
+		return from, to, errors.Wrapf(domain.ErrInvalidTimeRange, "range exceeds %v buckets", HistogramMaxBuckets)
+	}
+
+	return from, to, nil
+}
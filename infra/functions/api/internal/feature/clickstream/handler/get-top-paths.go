@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetTopPathsController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getTopPaths",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetTopPathsController")
+	defer span.Close(nil)
+
+	var query dto.TopPathsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	top, err := GetTopPathsService(ctx, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get top paths",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": top.DTO(),
+	})
+}
+
+// service.
+func GetTopPathsService(ctx context.Context, query *dto.TopPathsQuery) (domain.TopPaths, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getTopPaths",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetTopPathsService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var top domain.TopPaths
+
+	granularity := query.Period
+	if granularity == "" {
+		granularity = domain.GranularityDay
+	}
+	n := query.N
+	if n <= 0 {
+		n = DefaultTopPathsLen
+	}
+	at := time.Now()
+	if query.At != "" {
+		t, err := clickevent.ParseTimeBound(query.At)
+		if err != nil {
+			instrument.RecordGetTopPathsError(logger, span, err)
+			return top, err
+		}
+		at = t
+	}
+
+	top, err := GetTopPathsRepository(ctx, domain.PeriodSK(granularity, at), n)
+	if err != nil {
+		instrument.RecordGetTopPathsError(logger, span, err)
+		return top, err
+	}
+
+	return top, nil
+}
+
+// secondary adapter.
+// Every shard keeps its paths ordered by clicks in the TopClicksIndex,
+// so the top n of each shard are fetched concurrently and merged.
+// A sharded path ranks by the sum of its items that made the top n of their shard.
+func GetTopPathsRepository(ctx context.Context, period string, n int) (domain.TopPaths, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getTopPaths",
+		"component", "repository",
+		"period", period,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetTopPathsRepository")
+	defer span.Close(nil)
+
+	top := domain.TopPaths{Period: period}
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return top, err
+	}
+
+	shards := make([][]domain.TopPath, TopPathShards)
+	errs := make([]error, TopPathShards)
+
+	var wg sync.WaitGroup
+	for shard := 0; shard < TopPathShards; shard++ {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+
+			output, err := client.Query(ctx, &dynamodb.QueryInput{
+				TableName:              aws.String(TableName),
+				IndexName:              aws.String(TopClicksIndexName),
+				KeyConditionExpression: aws.String("PK = :pk"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":pk": &types.AttributeValueMemberS{Value: domain.TopPK(period, shard)},
+				},
+				ScanIndexForward: aws.Bool(false),
+				Limit:            aws.Int32(int32(n)),
+			})
+			if err != nil {
+				errs[shard] = err
+				return
+			}
+			errs[shard] = attributevalue.UnmarshalListOfMaps(output.Items, &shards[shard])
+		}(shard)
+	}
+	wg.Wait()
+
+	// a sharded path has an item in several shards, which add up to its clicks.
+	index := make(map[string]int)
+	for shard := range shards {
+		if errs[shard] != nil {
+			commoninstrument.RecordError(logger, span, errs[shard])
+			return top, errs[shard]
+		}
+		for _, path := range shards[shard] {
+			if i, ok := index[path.Path]; ok {
+				top.Paths[i].Clicks += path.Clicks
+				continue
+			}
+			index[path.Path] = len(top.Paths)
+			top.Paths = append(top.Paths, path)
+		}
+	}
+
+	sort.SliceStable(top.Paths, func(i, j int) bool {
+		if top.Paths[i].Clicks != top.Paths[j].Clicks {
+			return top.Paths[i].Clicks > top.Paths[j].Clicks
+		}
+		return top.Paths[i].Path < top.Paths[j].Path
+	})
+	if len(top.Paths) > n {
+		top.Paths = top.Paths[:n]
+	}
+
+	return top, nil
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/sketch"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetUniquesController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "getUniques",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetUniquesController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var query dto.UniquesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	uniques, err := GetUniquesService(ctx, path, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to get uniques for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": uniques.DTO(),
+	})
+}
+
+// service.
+// The sketches of the periods in range are merged, so visitors seen in several periods count once.
+func GetUniquesService(ctx context.Context, path string, query *dto.UniquesQuery) (domain.Uniques, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getUniques",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetUniquesService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	uniques := domain.Uniques{Path: path, Granularity: query.Granularity}
+	if uniques.Granularity == "" {
+		uniques.Granularity = domain.GranularityHour
+	}
+
+	from, to, err := seriesRange(uniques.Granularity, query.From, query.To)
+	if err != nil {
+		instrument.RecordGetUniquesError(logger, span, err)
+		return uniques, err
+	}
+	uniques.From, uniques.To = from, to
+
+	sketches, err := GetUniquesRepository(ctx, path, uniques.Granularity, from, to)
+	if err != nil {
+		instrument.RecordGetUniquesError(logger, span, err)
+		return uniques, err
+	}
+
+	merged, err := sketch.NewHyperLogLog(UniqueSketchPrecision)
+	if err != nil {
+		instrument.RecordGetUniquesError(logger, span, err)
+		return uniques, err
+	}
+	// the sketches of the shards of a period merge like those of different periods.
+	periods := make(map[string]bool, len(sketches))
+	for _, item := range sketches {
+		periods[item.SK] = true
+		var hll sketch.HyperLogLog
+		if err := hll.UnmarshalBinary(item.Sketch); err != nil {
+			instrument.RecordGetUniquesError(logger, span, err)
+			return uniques, err
+		}
+		if _, err := merged.Merge(&hll); err != nil {
+			instrument.RecordGetUniquesError(logger, span, err)
+			return uniques, err
+		}
+	}
+
+	uniques.Periods = len(periods)
+	uniques.Estimate = merged.Estimate()
+	uniques.StandardError = merged.StandardError()
+
+	return uniques, nil
+}
+
+// secondary adapter.
+func GetUniquesRepository(ctx context.Context, path, granularity string, from, to time.Time) ([]domain.UniqueSketch, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "getUniques",
+		"component", "repository",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetUniquesRepository")
+	defer span.Close(nil)
+
+	var sketches []domain.UniqueSketch
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return sketches, err
+	}
+
+	params := &dynamodb.QueryInput{
+		TableName:              aws.String(TableName),
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: domain.UniquePK(path)},
+			":lo": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, from)},
+			":hi": &types.AttributeValueMemberS{Value: domain.PeriodSK(granularity, to)},
+		},
+	}
+	items, err := queryAggregateShards(ctx, client, path, domain.UniquePK(path), params)
+	if err != nil {
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return sketches, err
+	}
+	if err := attributevalue.UnmarshalListOfMaps(items, &sketches); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return sketches, err
+	}
+
+	return sketches, nil
+}
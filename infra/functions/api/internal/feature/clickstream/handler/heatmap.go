@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+)
+
+// heatmapKey returns the grid cell counter of the event, for events that carry
+// page coordinates with the viewport and document size.
+func heatmapKey(event *domain.ClickEvent) (aggregateKey, bool) {
+	if event.X == nil || event.Y == nil || event.Viewport == nil {
+		return aggregateKey{}, false
+	}
+
+	row, col, ok := domain.HeatmapCell(*event.X, *event.Y, event.Document)
+	if !ok {
+		return aggregateKey{}, false
+	}
+
+	bucket := domain.ViewportBucket(event.Viewport.Width)
+	pk := domain.ShardedPK(domain.HeatPK(bucket, event.Path), domain.EventShard(event))
+	return aggregateKey{PK: pk, SK: domain.HeatSK(row, col)}, true
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/pubsub"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrLiveUnavailable = errors.New("live tail is not available")
+
+	liveHub pubsub.Hub
+)
+
+// UseLiveHub sets the hub that created click events are published to, by path.
+// Nothing is published until a hub is set.
+func UseLiveHub(hub pubsub.Hub) {
+	liveHub = hub
+}
+
+// publishLive leaves bots out of the feed, as the counters and the other reads do by default.
+func publishLive(event *domain.ClickEvent) {
+	if liveHub == nil || event.IsBot {
+		return
+	}
+	liveHub.Publish(event.Path, event.DTO())
+}
+
+// primary adapter.
+// It streams the click events created on the path as Server-Sent Events until the client leaves
+// or the hub drops it for falling behind.
+func LiveClickStreamController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "liveClickStream",
+		"component", "controller",
+	)
+
+	ctx, span := o11y.BeginSubSegment(c.Request.Context(), "LiveClickStreamController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	sub, err := LiveClickStreamService(ctx, path)
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(LiveHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case msg, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					instrument.RecordLiveClickStreamDropped(logger, path)
+					c.SSEvent("dropped", gin.H{
+						"message": "subscriber fell behind",
+					})
+				}
+				return false
+			}
+			c.SSEvent("click", msg)
+			return true
+		case t := <-heartbeat.C:
+			c.SSEvent("ping", t.UTC().Format(time.RFC3339))
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// service.
+func LiveClickStreamService(ctx context.Context, path string) (*pubsub.Subscription, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "liveClickStream",
+		"component", "service",
+		"path", path,
+	)
+
+	_, span := o11y.BeginSubSegment(ctx, "LiveClickStreamService")
+	defer span.Close(nil)
+
+	if liveHub == nil {
+		commoninstrument.RecordError(logger, span, ErrLiveUnavailable)
+		return nil, ErrLiveUnavailable
+	}
+
+	return liveHub.Subscribe(path), nil
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// service.
+// It rewrites click events older than clickevent.CurrentSchemaVersion with a parallel scan of segments.
+// Events written before retention also get the expiry of their createdAt under the retention rules,
+// so TTL deletes them like any other event. The rules come from the same CLICKSTREAM_RETENTION.
+func MigrateClickEventsService(ctx context.Context, segments int, dryRun bool) (domain.Migration, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "migrateClickEvents",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "MigrateClickEventsService")
+	defer span.Close(nil)
+
+	if segments < 1 {
+		segments = 1
+	}
+
+	var (
+		result domain.Migration
+		wg     sync.WaitGroup
+	)
+	errs := make([]error, segments)
+	for segment := 0; segment < segments; segment++ {
+		wg.Add(1)
+		go func(segment int) {
+			defer wg.Done()
+			errs[segment] = MigrateClickEventsRepository(ctx, segment, segments, dryRun, &result)
+		}(segment)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			instrument.RecordMigrateClickEventsError(logger, span, err)
+			return result, err
+		}
+	}
+
+	instrument.RecordMigrateClickEventsResult(logger, &result, dryRun)
+
+	return result, nil
+}
+
+// secondary adapter.
+// Each item is put back only if no one rewrote it since the scan; losing that race counts as a conflict,
+// since the writer already stored the current version.
+func MigrateClickEventsRepository(ctx context.Context, segment, segments int, dryRun bool, result *domain.Migration) error {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "migrateClickEvents",
+		"component", "repository",
+		"segment", segment,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "MigrateClickEventsRepository")
+	defer span.Close(nil)
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return err
+	}
+
+	params := &dynamodb.ScanInput{
+		TableName:        aws.String(TableName),
+		Segment:          aws.Int32(int32(segment)),
+		TotalSegments:    aws.Int32(int32(segments)),
+		FilterExpression: aws.String("begins_with(PK, :pk) AND (attribute_not_exists(schemaVersion) OR schemaVersion < :version OR attribute_not_exists(expiresAt))"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":      &types.AttributeValueMemberS{Value: domain.EventPKPrefix},
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(clickevent.CurrentSchemaVersion)},
+		},
+	}
+
+	paginator := dynamodb.NewScanPaginator(client, params)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			commoninstrument.SetParamsToSpanAttr(logger, span, params)
+			commoninstrument.RecordError(logger, span, err)
+			return err
+		}
+		atomic.AddInt64(&result.Scanned, int64(len(output.Items)))
+
+		for _, item := range output.Items {
+			if err := migrateClickEventItem(ctx, client, item, dryRun); err != nil {
+				var ccf *types.ConditionalCheckFailedException
+				if errors.As(err, &ccf) {
+					atomic.AddInt64(&result.Conflicts, 1)
+					continue
+				}
+				logger.Warn("failed to migrate item", "PK", item["PK"], "SK", item["SK"], "err", err)
+				atomic.AddInt64(&result.Failed, 1)
+				continue
+			}
+			atomic.AddInt64(&result.Migrated, 1)
+		}
+	}
+
+	return nil
+}
+
+// migrateClickEventItem works on the raw item, so attributes unknown to the model are kept.
+func migrateClickEventItem(ctx context.Context, client *dynamodb.Client, item map[string]types.AttributeValue, dryRun bool) error {
+	condition := "attribute_not_exists(schemaVersion)"
+	values := map[string]types.AttributeValue{}
+	if v, ok := item["schemaVersion"]; ok {
+		condition = "schemaVersion = :version"
+		values[":version"] = v
+	}
+
+	if _, err := clickevent.UpgradeItem(item); err != nil {
+		return err
+	}
+
+	// the expiry is only ever added, never moved, so a concurrent writer keeps its own.
+	if _, ok := item["expiresAt"]; !ok {
+		var event domain.ClickEvent
+		if err := attributevalue.UnmarshalMap(item, &event); err != nil {
+			return err
+		}
+		if _, err := time.Parse(time.RFC3339, event.CreatedAt); err == nil {
+			withRetention(&event)
+			item["expiresAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(event.ExpiresAt, 10)}
+			condition += " AND attribute_not_exists(expiresAt)"
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	params := &dynamodb.PutItemInput{
+		TableName:           aws.String(TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_exists(PK) AND " + condition),
+	}
+	if len(values) > 0 {
+		params.ExpressionAttributeValues = values
+	}
+	_, err := client.PutItem(ctx, params)
+	return err
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func PromoteShardsController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "clickstream",
+		"usecase", "promoteShards",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "PromoteShardsController")
+	defer span.Close(nil)
+
+	path := c.Param("path")
+
+	var req dto.ShardConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid body",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&req); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	config, err := PromoteShardsService(ctx, path, &req)
+	if err != nil {
+		if errors.Is(err, domain.ErrShardDemotion) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to promote shards for path",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": config.DTO(),
+	})
+}
+
+// service.
+func PromoteShardsService(ctx context.Context, path string, req *dto.ShardConfigRequest) (domain.ShardConfig, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "promoteShards",
+		"component", "service",
+		"path", path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "PromoteShardsService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, req)
+
+	config := domain.DefaultShardConfig(path)
+	config.Shards = req.Shards
+	config.Strategy = req.Strategy
+	config.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	config, err := PromoteShardsRepository(ctx, &config)
+	if err != nil {
+		instrument.RecordPromoteShardsError(logger, span, err)
+		return config, err
+	}
+
+	instrument.RecordPromoteShardsCompleted(logger, config.Shards)
+
+	return config, nil
+}
+
+// secondary adapter.
+// The shard count only grows: readers cover shards [0, shards), so lowering it would hide
+// the events already written to the dropped shards.
+func PromoteShardsRepository(ctx context.Context, config *domain.ShardConfig) (domain.ShardConfig, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "clickstream",
+		"usecase", "promoteShards",
+		"component", "repository",
+		"path", config.Path,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "PromoteShardsRepository")
+	defer span.Close(nil)
+
+	var updated domain.ShardConfig
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return updated, err
+	}
+
+	// a promotion without a strategy keeps the current one.
+	strategy := "#strategy = :strategy"
+	if config.Strategy == "" {
+		strategy = "#strategy = if_not_exists(#strategy, :strategy)"
+		config.Strategy = domain.ShardStrategyRandom
+	}
+
+	params := &dynamodb.UpdateItemInput{
+		TableName: aws.String(TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: config.PK},
+			"SK": &types.AttributeValueMemberS{Value: config.SK},
+		},
+		UpdateExpression:    aws.String("SET #shards = :shards, " + strategy + ", #path = :path, updatedAt = :updatedAt"),
+		ConditionExpression: aws.String("attribute_not_exists(#shards) OR #shards <= :shards"),
+		ExpressionAttributeNames: map[string]string{
+			"#shards":   "shards",
+			"#strategy": "strategy",
+			"#path":     "path",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":shards":    &types.AttributeValueMemberN{Value: strconv.Itoa(config.Shards)},
+			":strategy":  &types.AttributeValueMemberS{Value: config.Strategy},
+			":path":      &types.AttributeValueMemberS{Value: config.Path},
+			":updatedAt": &types.AttributeValueMemberS{Value: config.UpdatedAt},
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	}
+	output, err := client.UpdateItem(ctx, params)
+	if err != nil {
+		var ccf *types.ConditionalCheckFailedException
+		if errors.As(err, &ccf) {
+			return updated, errors.Wrapf(domain.ErrShardDemotion, "%v", config.Path)
+		}
+		commoninstrument.SetParamsToSpanAttr(logger, span, params)
+		commoninstrument.RecordError(logger, span, err)
+		return updated, err
+	}
+
+	if err := attributevalue.UnmarshalMap(output.Attributes, &updated); err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return updated, err
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+type retentionRule struct {
+	Prefix    string
+	Retention time.Duration
+}
+
+var (
+	retentionOnce  sync.Once
+	retentionRules []retentionRule
+	retentionAll   = DefaultRetention
+)
+
+// retentionFor returns the retention period of raw click events on the path.
+// Rules come from CLICKSTREAM_RETENTION, e.g. "*=90d,checkout=30d,admin/=7d",
+// and the longest matching path prefix wins.
+func retentionFor(path string) time.Duration {
+	retentionOnce.Do(func() {
+		retentionAll, retentionRules = parseRetentionRules(os.Getenv("CLICKSTREAM_RETENTION"))
+	})
+
+	for _, rule := range retentionRules {
+		if strings.HasPrefix(path, rule.Prefix) {
+			return rule.Retention
+		}
+	}
+	return retentionAll
+}
+
+// withRetention stamps the event with the epoch second at which DynamoDB TTL may delete it.
+func withRetention(event *domain.ClickEvent) {
+	createdAt, err := time.Parse(time.RFC3339, event.CreatedAt)
+	if err != nil {
+		createdAt = time.Now()
+	}
+	event.ExpiresAt = createdAt.Add(retentionFor(event.Path)).Unix()
+}
+
+func parseRetentionRules(value string) (time.Duration, []retentionRule) {
+	logger := slogger.New()
+
+	fallback := DefaultRetention
+	rules := make([]retentionRule, 0)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		prefix, raw, ok := strings.Cut(entry, "=")
+		if !ok {
+			logger.Warn("ignore invalid retention rule", "rule", entry)
+			continue
+		}
+		retention, err := parseRetention(strings.TrimSpace(raw))
+		if err != nil {
+			logger.Warn("ignore invalid retention rule", "rule", entry, "err", err)
+			continue
+		}
+
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "*" {
+			fallback = retention
+			continue
+		}
+		rules = append(rules, retentionRule{Prefix: prefix, Retention: retention})
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Prefix) > len(rules[j].Prefix)
+	})
+
+	return fallback, rules
+}
+
+// parseRetention accepts Go durations and whole days such as "90d".
+func parseRetention(value string) (time.Duration, error) {
+	var retention time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		retention = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		retention = d
+	}
+
+	if retention <= 0 {
+		return 0, errors.Errorf("retention must be positive: %v", value)
+	}
+	return retention, nil
+}
@@ -0,0 +1,324 @@
+package handler
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/cursor"
+)
+
+const shardCountAttr = "shards"
+
+type cachedShards struct {
+	config    domain.ShardConfig
+	expiresAt time.Time
+}
+
+var (
+	shardCacheMu sync.Mutex
+	shardCache   = make(map[string]cachedShards)
+)
+
+// loadShardConfig reads the shard config of the path, a path without one has the single legacy shard.
+func loadShardConfig(ctx context.Context, client *dynamodb.Client, path string) (domain.ShardConfig, error) {
+	config := domain.DefaultShardConfig(path)
+
+	output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(TableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: domain.ShardPK(path)},
+			"SK": &types.AttributeValueMemberS{Value: domain.ShardSK},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return config, err
+	}
+	if len(output.Item) == 0 {
+		return config, nil
+	}
+
+	if err := attributevalue.UnmarshalMap(output.Item, &config); err != nil {
+		return domain.DefaultShardConfig(path), err
+	}
+	return config, nil
+}
+
+// cachedShardConfig serves the write path, which may keep writing to the old shards for up to
+// ShardConfigCacheTTL after a promotion. Those shards are still read, so no event is lost.
+func cachedShardConfig(ctx context.Context, client *dynamodb.Client, path string) (domain.ShardConfig, error) {
+	shardCacheMu.Lock()
+	cached, ok := shardCache[path]
+	shardCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.config, nil
+	}
+
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		return config, err
+	}
+
+	shardCacheMu.Lock()
+	shardCache[path] = cachedShards{config: config, expiresAt: time.Now().Add(ShardConfigCacheTTL)}
+	shardCacheMu.Unlock()
+
+	return config, nil
+}
+
+// withShard moves the event to the partition of its shard. When the config can not be read
+// the event stays on the legacy shard, which is always read.
+func withShard(ctx context.Context, client *dynamodb.Client, event *domain.ClickEvent) error {
+	config, err := cachedShardConfig(ctx, client, event.Path)
+	if err != nil {
+		return err
+	}
+	event.PK = domain.EventShardPK(event.Path, config.ShardFor(event))
+	return nil
+}
+
+// queryAggregateShards runs the query, whose partition is bound to :pk, on every shard of the aggregate pk
+// and returns the items of all of them. Shards are only ever added, so [0, Shards) holds every increment.
+func queryAggregateShards(ctx context.Context, client *dynamodb.Client, path, pk string, params *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	config, err := loadShardConfig(ctx, client, path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]map[string]types.AttributeValue, 0)
+	for shard := 0; shard < config.Shards; shard++ {
+		input := *params
+		input.ExpressionAttributeValues = maps.Clone(params.ExpressionAttributeValues)
+		input.ExpressionAttributeValues[":pk"] = &types.AttributeValueMemberS{Value: domain.ShardedPK(pk, shard)}
+
+		paginator := dynamodb.NewQueryPaginator(client, &input)
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, output.Items...)
+		}
+	}
+
+	return items, nil
+}
+
+// shardStarts holds the ExclusiveStartKey of every shard that still has events to read.
+// A nil key means the shard is read from the start of the range.
+type shardStarts map[int]map[string]types.AttributeValue
+
+func newShardStarts(shards int) shardStarts {
+	starts := make(shardStarts, shards)
+	for shard := 0; shard < shards; shard++ {
+		starts[shard] = nil
+	}
+	return starts
+}
+
+// decodeShardCursor restores the shard positions of a cursor.
+// Shards added by a promotion after the cursor was issued are read from the start.
+func decodeShardCursor(scope, token, path string, shards int) (shardStarts, error) {
+	if token == "" {
+		return newShardStarts(shards), nil
+	}
+
+	key, err := cursor.Decode(scope, token)
+	if err != nil {
+		return nil, err
+	}
+
+	issued := 1
+	if value, ok := key[shardCountAttr].(*types.AttributeValueMemberN); ok {
+		if issued, err = strconv.Atoi(value.Value); err != nil {
+			return nil, cursor.ErrInvalidCursor
+		}
+	}
+
+	starts := make(shardStarts)
+	for k, v := range key {
+		if k == shardCountAttr {
+			continue
+		}
+		shard, err := strconv.Atoi(k)
+		sk, ok := v.(*types.AttributeValueMemberS)
+		if err != nil || !ok || shard < 0 || shard >= max(shards, issued) {
+			return nil, cursor.ErrInvalidCursor
+		}
+		if sk.Value == "" {
+			starts[shard] = nil
+			continue
+		}
+		starts[shard] = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: domain.EventShardPK(path, shard)},
+			"SK": &types.AttributeValueMemberS{Value: sk.Value},
+		}
+	}
+	for shard := issued; shard < shards; shard++ {
+		starts[shard] = nil
+	}
+
+	return starts, nil
+}
+
+// encodeShardCursor flattens the shard positions into a cursor, keyed by shard with the SK to resume after.
+// It returns an empty cursor once every shard is read.
+func encodeShardCursor(scope string, shards int, starts shardStarts) (string, error) {
+	if len(starts) == 0 {
+		return "", nil
+	}
+
+	key := map[string]types.AttributeValue{
+		shardCountAttr: &types.AttributeValueMemberN{Value: strconv.Itoa(shards)},
+	}
+	for shard, start := range starts {
+		sk := ""
+		if value, ok := start["SK"].(*types.AttributeValueMemberS); ok {
+			sk = value.Value
+		}
+		key[strconv.Itoa(shard)] = &types.AttributeValueMemberS{Value: sk}
+	}
+
+	return cursor.Encode(scope, key)
+}
+
+// shardedEventQuery reads the events of a path in a SK range across all of its shards.
+type shardedEventQuery struct {
+	client      *dynamodb.Client
+	path        string
+	lo          string
+	hi          string
+	includeBots bool
+	starts      shardStarts
+}
+
+type shardPage struct {
+	events []domain.ClickEvent
+	last   map[string]types.AttributeValue
+	err    error
+}
+
+func (q *shardedEventQuery) done() bool {
+	return len(q.starts) == 0
+}
+
+// next returns at most limit events of the remaining shards in SK order,
+// querying again while the page is short and some shard still has events.
+func (q *shardedEventQuery) next(ctx context.Context, limit int32) ([]domain.ClickEvent, error) {
+	merged := make([]domain.ClickEvent, 0, limit)
+	for len(merged) < int(limit) && !q.done() {
+		events, err := q.round(ctx, limit-int32(len(merged)))
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, events...)
+	}
+	return merged, nil
+}
+
+// round queries the remaining shards concurrently and merge-sorts them by SK, returning at most limit events.
+// A shard that stopped at its LastEvaluatedKey may still hold events below those of the other shards,
+// so only events up to the lowest such key are returned. Every shard then resumes after the last of
+// its events that made it into the page; the shard with the lowest key always moves on to it.
+func (q *shardedEventQuery) round(ctx context.Context, limit int32) ([]domain.ClickEvent, error) {
+	shards := make([]int, 0, len(q.starts))
+	for shard := range q.starts {
+		shards = append(shards, shard)
+	}
+
+	var wg sync.WaitGroup
+	pages := make([]shardPage, len(shards))
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i, shard int) {
+			defer wg.Done()
+			pages[i] = q.query(ctx, shard, limit)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	bound := ""
+	for _, page := range pages {
+		if page.err != nil {
+			return nil, page.err
+		}
+		if sk, ok := page.last["SK"].(*types.AttributeValueMemberS); ok && (bound == "" || sk.Value < bound) {
+			bound = sk.Value
+		}
+	}
+
+	merged := make([]domain.ClickEvent, 0, limit)
+	taken := make([]int, len(pages))
+	for len(merged) < int(limit) {
+		pick := -1
+		for i, page := range pages {
+			if taken[i] == len(page.events) {
+				continue
+			}
+			if pick == -1 || page.events[taken[i]].SK < pages[pick].events[taken[pick]].SK {
+				pick = i
+			}
+		}
+		if pick == -1 {
+			break
+		}
+		event := pages[pick].events[taken[pick]]
+		if bound != "" && event.SK > bound {
+			break
+		}
+		merged = append(merged, event)
+		taken[pick]++
+	}
+
+	for i, shard := range shards {
+		page := pages[i]
+		switch {
+		case taken[i] == len(page.events) && len(page.last) == 0:
+			delete(q.starts, shard)
+		case taken[i] == len(page.events):
+			q.starts[shard] = page.last
+		case taken[i] > 0:
+			q.starts[shard] = map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: domain.EventShardPK(q.path, shard)},
+				"SK": &types.AttributeValueMemberS{Value: page.events[taken[i]-1].SK},
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+func (q *shardedEventQuery) query(ctx context.Context, shard int, limit int32) shardPage {
+	values := map[string]types.AttributeValue{
+		":pk": &types.AttributeValueMemberS{Value: domain.EventShardPK(q.path, shard)},
+		":lo": &types.AttributeValueMemberS{Value: q.lo},
+		":hi": &types.AttributeValueMemberS{Value: q.hi},
+	}
+	output, err := q.client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(TableName),
+		KeyConditionExpression:    aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ExpressionAttributeValues: values,
+		FilterExpression:          clickevent.Filter("", q.includeBots, values),
+		Limit:                     aws.Int32(limit),
+		ExclusiveStartKey:         q.starts[shard],
+	})
+	if err != nil {
+		return shardPage{err: err}
+	}
+
+	var page shardPage
+	if err := clickevent.UnmarshalItems(output.Items, &page.events); err != nil {
+		return shardPage{err: err}
+	}
+	page.last = output.LastEvaluatedKey
+	return page
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+)
+
+var errSketchContended = errors.New("sketch is contended")
+
+// sketchMerge receives the stored sketch, nil for a new item, and returns the sketch to store
+// or false when the merge changed nothing.
+type sketchMerge func(stored []byte) ([]byte, bool, error)
+
+// mergeSketchItem folds a sketch into the binary attribute of a period item under an optimistic version check.
+// Sketches can not be merged by an update expression, so they are read, merged and written back,
+// retrying with backoff when another writer got in between.
+func mergeSketchItem(ctx context.Context, client *dynamodb.Client, key aggregateKey, attr, path, period string, merge sketchMerge) error {
+	itemKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: key.PK},
+		"SK": &types.AttributeValueMemberS{Value: key.SK},
+	}
+
+	for attempt := 0; attempt < SketchMergeMaxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepWithContext(ctx, backoff(attempt)); err != nil {
+				return err
+			}
+		}
+		output, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(TableName),
+			Key:            itemKey,
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+
+		var stored []byte
+		if value, ok := output.Item[attr].(*types.AttributeValueMemberB); ok {
+			stored = value.Value
+		}
+		version := int64(0)
+		if value, ok := output.Item["version"].(*types.AttributeValueMemberN); ok {
+			if version, err = strconv.ParseInt(value.Value, 10, 64); err != nil {
+				return err
+			}
+		}
+
+		data, changed, err := merge(stored)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return nil
+		}
+
+		_, err = client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName:           aws.String(TableName),
+			Key:                 itemKey,
+			UpdateExpression:    aws.String("SET #sketch = :sketch, #version = :next, #path = :path, #period = :period"),
+			ConditionExpression: aws.String("attribute_not_exists(#version) OR #version = :version"),
+			ExpressionAttributeNames: map[string]string{
+				"#sketch":  attr,
+				"#version": "version",
+				"#path":    "path",
+				"#period":  "period",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":sketch":  &types.AttributeValueMemberB{Value: data},
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
+				":next":    &types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)},
+				":path":    &types.AttributeValueMemberS{Value: path},
+				":period":  &types.AttributeValueMemberS{Value: period},
+			},
+		})
+		if err == nil {
+			return nil
+		}
+		var ccf *types.ConditionalCheckFailedException
+		if !errors.As(err, &ccf) {
+			return err
+		}
+	}
+
+	return errSketchContended
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/sketch"
+	"github.com/pkg/errors"
+)
+
+type uniqueDelta struct {
+	Path   string
+	Period string
+	Sketch *sketch.HyperLogLog
+}
+
+// applyUniqueSketches adds the visitors of the events to the hour and day sketches of their paths.
+// A sketch is a read-modify-write of a binary attribute, so it can not join the event transaction
+// and is updated from the table stream once the events are persisted. Like the counters,
+// sketches are split by event shard.
+func applyUniqueSketches(ctx context.Context, client *dynamodb.Client, events []domain.ClickEvent) error {
+	keys := make([]aggregateKey, 0)
+	deltas := make(map[aggregateKey]*uniqueDelta)
+
+	for i := range events {
+		if events[i].IsBot || events[i].VisitorID == "" {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, events[i].CreatedAt)
+		if err != nil {
+			continue
+		}
+		path := events[i].Path
+		pk := domain.ShardedPK(domain.UniquePK(path), domain.EventShard(&events[i]))
+
+		for _, granularity := range []string{domain.GranularityHour, domain.GranularityDay} {
+			period := domain.PeriodSK(granularity, createdAt)
+			key := aggregateKey{PK: pk, SK: period}
+			delta, ok := deltas[key]
+			if !ok {
+				hll, err := sketch.NewHyperLogLog(UniqueSketchPrecision)
+				if err != nil {
+					return err
+				}
+				delta = &uniqueDelta{Path: path, Period: period, Sketch: hll}
+				keys = append(keys, key)
+				deltas[key] = delta
+			}
+			delta.Sketch.Add(events[i].VisitorID)
+		}
+	}
+
+	// a failed sketch does not stop the others, the first failure is reported.
+	var firstErr error
+	for _, key := range keys {
+		if err := mergeUniqueSketch(ctx, client, key, deltas[key]); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "%v %v", key.PK, key.SK)
+		}
+	}
+	return firstErr
+}
+
+// mergeUniqueSketch merges the delta into the stored sketch.
+// Once a sketch is populated most visitors change no register, and those merges skip the write.
+func mergeUniqueSketch(ctx context.Context, client *dynamodb.Client, key aggregateKey, delta *uniqueDelta) error {
+	return mergeSketchItem(ctx, client, key, "hll", delta.Path, delta.Period, func(stored []byte) ([]byte, bool, error) {
+		merged, err := sketch.NewHyperLogLog(UniqueSketchPrecision)
+		if err != nil {
+			return nil, false, err
+		}
+		if stored != nil {
+			if err := merged.UnmarshalBinary(stored); err != nil {
+				return nil, false, err
+			}
+		}
+
+		changed, err := merged.Merge(delta.Sketch)
+		if err != nil || !changed {
+			return nil, false, err
+		}
+
+		data, err := merged.MarshalBinary()
+		return data, true, err
+	})
+}
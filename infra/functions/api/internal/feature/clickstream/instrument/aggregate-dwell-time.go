@@ -0,0 +1,31 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordAggregateDwellTimeError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to aggregate dwell time").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseAggregateDwellTimeErrorCount(logger)
+}
+
+func RecordDwellSamplesDropped(logger *slogger.Logger, span *xray.Segment, err error, samples int) {
+	logger.Warn(errors.Wrap(err, "dropped dwell samples").Error(), "samples", samples)
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseDwellSamplesDroppedCount(logger, samples)
+}
+
+func increaseAggregateDwellTimeErrorCount(logger *slogger.Logger) {
+	logger.Info("AggregateDwellTime", "Error", 1)
+}
+
+func increaseDwellSamplesDroppedCount(logger *slogger.Logger, samples int) {
+	logger.Info("AggregateDwellTime", "Dropped", samples)
+}
@@ -0,0 +1,19 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordAggregateUniquesError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to aggregate uniques").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseAggregateUniquesErrorCount(logger)
+}
+
+func increaseAggregateUniquesErrorCount(logger *slogger.Logger) {
+	logger.Info("AggregateUniques", "Error", 1)
+}
@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordBatchCreateClickEventsSuccess(logger *slogger.Logger, accepted, rejected int) {
+	logger.Info("batch create click-events success", "accepted", accepted, "rejected", rejected)
+	increaseBatchCreateClickEventsCount(logger, accepted, rejected)
+}
+
+func RecordBatchCreateClickEventsError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to batch create click-events").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseBatchCreateClickEventsErrorCount(logger)
+}
+
+func increaseBatchCreateClickEventsCount(logger *slogger.Logger, accepted, rejected int) {
+	logger.Info("BatchCreateClickEvents", "Accepted", accepted, "Rejected", rejected)
+}
+
+func increaseBatchCreateClickEventsErrorCount(logger *slogger.Logger) {
+	logger.Info("BatchCreateClickEvents", "Error", 1)
+}
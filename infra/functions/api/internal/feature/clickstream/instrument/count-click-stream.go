@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordCountClickStreamTruncated(logger *slogger.Logger, scannedPages int) {
+	logger.Warn("count click-stream truncated by budget", "scannedPages", scannedPages)
+	increaseCountClickStreamTruncatedCount(logger)
+}
+
+func RecordCountClickStreamError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to count click-stream").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseCountClickStreamErrorCount(logger)
+}
+
+func increaseCountClickStreamTruncatedCount(logger *slogger.Logger) {
+	logger.Info("CountClickStream", "Truncated", 1)
+}
+
+func increaseCountClickStreamErrorCount(logger *slogger.Logger) {
+	logger.Info("CountClickStream", "Error", 1)
+}
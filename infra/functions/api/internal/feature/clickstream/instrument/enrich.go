@@ -0,0 +1,29 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordEnrichDropped(logger *slogger.Logger, reason string, events int) {
+	logger.Info("events dropped by enricher", "reason", reason, "events", events)
+	increaseEnrichDroppedCount(logger, events)
+}
+
+// RecordEnrichStageError records a failed stage; the events of an open stage are stored without it.
+func RecordEnrichStageError(logger *slogger.Logger, span *xray.Segment, err error, policy string) {
+	logger.Error(errors.Wrap(err, "fail to enrich click-events").Error(), "policy", policy)
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseEnrichErrorCount(logger)
+}
+
+func increaseEnrichDroppedCount(logger *slogger.Logger, events int) {
+	logger.Info("Enrich", "Dropped", events)
+}
+
+func increaseEnrichErrorCount(logger *slogger.Logger) {
+	logger.Info("Enrich", "Error", 1)
+}
@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordEraseSubjectCompleted(logger *slogger.Logger, deleted int64) {
+	logger.Info("subject erased", "deleted", deleted)
+	increaseEraseSubjectCompletedCount(logger)
+}
+
+func RecordEraseSubjectError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to erase subject").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseEraseSubjectErrorCount(logger)
+}
+
+func increaseEraseSubjectCompletedCount(logger *slogger.Logger) {
+	logger.Info("EraseSubject", "Completed", 1)
+}
+
+func increaseEraseSubjectErrorCount(logger *slogger.Logger) {
+	logger.Info("EraseSubject", "Error", 1)
+}
@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordExportClickStreamRows(logger *slogger.Logger, rows int, partial bool) {
+	logger.Info("click-stream exported", "rows", rows, "partial", partial)
+	increaseExportClickStreamRowCount(logger, rows)
+}
+
+func RecordExportClickStreamError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to export click-stream").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseExportClickStreamErrorCount(logger)
+}
+
+func increaseExportClickStreamRowCount(logger *slogger.Logger, rows int) {
+	logger.Info("ExportClickStream", "Rows", rows)
+}
+
+func increaseExportClickStreamErrorCount(logger *slogger.Logger) {
+	logger.Info("ExportClickStream", "Error", 1)
+}
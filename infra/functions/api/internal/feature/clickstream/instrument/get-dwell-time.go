@@ -0,0 +1,19 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordGetDwellTimeError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to get dwell time").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseGetDwellTimeErrorCount(logger)
+}
+
+func increaseGetDwellTimeErrorCount(logger *slogger.Logger) {
+	logger.Info("GetDwellTime", "Error", 1)
+}
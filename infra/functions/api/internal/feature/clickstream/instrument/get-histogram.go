@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordGetHistogramTruncated(logger *slogger.Logger, scannedPages int) {
+	logger.Warn("histogram truncated by budget", "scannedPages", scannedPages)
+	increaseGetHistogramTruncatedCount(logger)
+}
+
+func RecordGetHistogramError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to get histogram").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseGetHistogramErrorCount(logger)
+}
+
+func increaseGetHistogramTruncatedCount(logger *slogger.Logger) {
+	logger.Info("GetHistogram", "Truncated", 1)
+}
+
+func increaseGetHistogramErrorCount(logger *slogger.Logger) {
+	logger.Info("GetHistogram", "Error", 1)
+}
@@ -0,0 +1,14 @@
+package instrument
+
+import (
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+)
+
+func RecordLiveClickStreamDropped(logger *slogger.Logger, path string) {
+	logger.Warn("live click-stream subscriber dropped", "path", path)
+	increaseLiveClickStreamDroppedCount(logger)
+}
+
+func increaseLiveClickStreamDroppedCount(logger *slogger.Logger) {
+	logger.Info("LiveClickStream", "Dropped", 1)
+}
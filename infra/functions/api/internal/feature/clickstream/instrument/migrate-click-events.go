@@ -0,0 +1,35 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/clickstream/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordMigrateClickEventsResult(logger *slogger.Logger, result *domain.Migration, dryRun bool) {
+	logger.Info("migrate click-events done",
+		"scanned", result.Scanned,
+		"migrated", result.Migrated,
+		"conflicts", result.Conflicts,
+		"failed", result.Failed,
+		"dryRun", dryRun,
+	)
+	increaseMigrateClickEventsCount(logger, result.Migrated)
+}
+
+func RecordMigrateClickEventsError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to migrate click-events").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increaseMigrateClickEventsErrorCount(logger)
+}
+
+func increaseMigrateClickEventsCount(logger *slogger.Logger, migrated int64) {
+	logger.Info("MigrateClickEvents", "Migrated", migrated)
+}
+
+func increaseMigrateClickEventsErrorCount(logger *slogger.Logger) {
+	logger.Info("MigrateClickEvents", "Error", 1)
+}
@@ -0,0 +1,28 @@
+package instrument
+
+import (
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+func RecordPromoteShardsCompleted(logger *slogger.Logger, shards int) {
+	logger.Info("shards promoted", "shards", shards)
+	increasePromoteShardsCompletedCount(logger)
+}
+
+func RecordPromoteShardsError(logger *slogger.Logger, span *xray.Segment, err error) {
+	logger.Error(errors.Wrap(err, "fail to promote shards").Error())
+	if span != nil {
+		_ = span.AddError(errors.WithStack(err))
+	}
+	increasePromoteShardsErrorCount(logger)
+}
+
+func increasePromoteShardsCompletedCount(logger *slogger.Logger) {
+	logger.Info("PromoteShards", "Completed", 1)
+}
+
+func increasePromoteShardsErrorCount(logger *slogger.Logger) {
+	logger.Info("PromoteShards", "Error", 1)
+}
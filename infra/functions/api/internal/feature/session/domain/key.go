@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	EventSKPrefix = clickevent.SKPrefix
+
+	inferredIDPrefix = "iv."
+)
+
+var (
+	ErrInvalidSessionID = errors.New("invalid session id")
+	ErrInvalidTimeRange = clickevent.ErrInvalidTimeRange
+	ErrSessionNotFound  = errors.New("session not found")
+)
+
+func EventSK(id string) string {
+	return clickevent.SK(id)
+}
+
+// InferredSessionID identifies a session without a client session id by its visitor and first event.
+func InferredSessionID(visitorID, firstEventID string) string {
+	return inferredIDPrefix + base64.RawURLEncoding.EncodeToString([]byte(visitorID)) + "." + firstEventID
+}
+
+func IsInferredSessionID(id string) bool {
+	return strings.HasPrefix(id, inferredIDPrefix)
+}
+
+// ParseInferredSessionID returns the visitor id and the first event id of an inferred session.
+func ParseInferredSessionID(id string) (string, string, error) {
+	encoded, firstEventID, ok := strings.Cut(strings.TrimPrefix(id, inferredIDPrefix), ".")
+	if !ok {
+		return "", "", ErrInvalidSessionID
+	}
+	visitorID, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(visitorID) == 0 {
+		return "", "", ErrInvalidSessionID
+	}
+	if _, err := ulid.ParseStrict(firstEventID); err != nil {
+		return "", "", ErrInvalidSessionID
+	}
+	return string(visitorID), firstEventID, nil
+}
@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/dto"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	SourceClient   = "client"
+	SourceInferred = "inferred"
+)
+
+// ClickEvent is the part of a stored click event that sessions are built from.
+type ClickEvent struct {
+	PK        string `json:"PK" dynamodbav:"PK"`
+	SK        string `json:"SK" dynamodbav:"SK"`
+	ID        string `json:"id" dynamodbav:"id"`
+	Path      string `json:"path" dynamodbav:"path"`
+	SessionID string `json:"sessionId,omitempty" dynamodbav:"sessionId,omitempty"`
+	VisitorID string `json:"visitorId,omitempty" dynamodbav:"visitorId,omitempty"`
+	CreatedAt string `json:"createdAt" dynamodbav:"createdAt"`
+}
+
+// Time returns the server-side time of the event, which is encoded in its ULID.
+func (d *ClickEvent) Time() time.Time {
+	id, err := ulid.ParseStrict(d.ID)
+	if err != nil {
+		t, _ := time.Parse(time.RFC3339, d.CreatedAt)
+		return t
+	}
+	return ulid.Time(id.Time())
+}
+
+type Session struct {
+	ID        string
+	VisitorID string
+	Source    string
+	Start     time.Time
+	End       time.Time
+	EntryPath string
+	ExitPath  string
+	Paths     []string
+}
+
+func (d *Session) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+func (d *Session) DTO() dto.Session {
+	return dto.Session{
+		ID:              d.ID,
+		VisitorID:       d.VisitorID,
+		Source:          d.Source,
+		Start:           d.Start.UTC().Format(time.RFC3339Nano),
+		End:             d.End.UTC().Format(time.RFC3339Nano),
+		DurationSeconds: d.Duration().Seconds(),
+		EntryPath:       d.EntryPath,
+		ExitPath:        d.ExitPath,
+		Paths:           d.Paths,
+		EventCount:      len(d.Paths),
+	}
+}
+
+func SessionsDTO(sessions []Session) []dto.Session {
+	result := make([]dto.Session, 0, len(sessions))
+	for i := range sessions {
+		result = append(result, sessions[i].DTO())
+	}
+	return result
+}
@@ -0,0 +1,27 @@
+package dto
+
+type Session struct {
+	ID              string   `json:"id"`
+	VisitorID       string   `json:"visitorId,omitempty"`
+	Source          string   `json:"source"`
+	Start           string   `json:"start"`
+	End             string   `json:"end"`
+	DurationSeconds float64  `json:"durationSeconds"`
+	EntryPath       string   `json:"entryPath"`
+	ExitPath        string   `json:"exitPath"`
+	Paths           []string `json:"paths"`
+	EventCount      int      `json:"eventCount"`
+}
+
+type SessionQuery struct {
+	Gap         string `form:"gap" json:"gap"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
+
+type SessionListQuery struct {
+	VisitorID   string `form:"visitorId" json:"visitorId" validate:"required,max=128"`
+	From        string `form:"from" json:"from"`
+	To          string `form:"to" json:"to"`
+	Gap         string `form:"gap" json:"gap"`
+	IncludeBots bool   `form:"includeBots" json:"includeBots"`
+}
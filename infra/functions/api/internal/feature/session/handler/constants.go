@@ -0,0 +1,20 @@
+package handler
+
+import "time"
+
+const (
+	// Controller.
+	// The deadline budgets are taken from it, so it must stay below the function timeout of the stack.
+	ControllerTimeout = time.Second * 10
+
+	// Repository.
+	TableName        = "clickstream"
+	VisitorIndexName = "VisitorIndex"
+	SessionIndexName = "SessionIndex"
+
+	// Session.
+	DefaultInactivityGap = time.Minute * 30
+	MinInactivityGap     = time.Minute
+	MaxInactivityGap     = time.Hour * 24
+	SessionMaxEvents     = 5000
+)
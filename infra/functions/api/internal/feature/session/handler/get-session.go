@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func GetSessionController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "session",
+		"usecase", "getSession",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetSessionController")
+	defer span.Close(nil)
+
+	id := c.Param("id")
+
+	var query dto.SessionQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+
+	session, err := GetSessionService(ctx, id, &query)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrSessionNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "session not found",
+			})
+		case errors.Is(err, domain.ErrInvalidSessionID), errors.Is(err, ErrInvalidGap):
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+		default:
+			commoninstrument.RecordError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"message": "failed to get session",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": session.DTO(),
+	})
+}
+
+// service.
+func GetSessionService(ctx context.Context, id string, query *dto.SessionQuery) (domain.Session, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "session",
+		"usecase", "getSession",
+		"component", "service",
+		"id", id,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetSessionService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	var session domain.Session
+
+	gap, err := parseGap(query.Gap)
+	if err != nil {
+		return session, err
+	}
+
+	events, err := GetSessionRepository(ctx, id, gap, query.IncludeBots)
+	if err != nil {
+		instrument.RecordGetSessionError(logger, span, err)
+		return session, err
+	}
+
+	for _, s := range sessionize(events, gap) {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+
+	return session, domain.ErrSessionNotFound
+}
+
+// secondary adapter.
+// Client sessions are read from the SessionIndex. Inferred sessions are read from the VisitorIndex,
+// starting at their first event and stopping at the first inactivity gap.
+func GetSessionRepository(ctx context.Context, id string, gap time.Duration, includeBots bool) ([]domain.ClickEvent, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "session",
+		"usecase", "getSession",
+		"component", "repository",
+		"id", id,
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "GetSessionRepository")
+	defer span.Close(nil)
+
+	var events []domain.ClickEvent
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return events, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":id": &types.AttributeValueMemberS{Value: id},
+	}
+	params := &dynamodb.QueryInput{
+		TableName:                 aws.String(TableName),
+		IndexName:                 aws.String(SessionIndexName),
+		KeyConditionExpression:    aws.String("sessionId = :id"),
+		FilterExpression:          clickevent.Filter("", includeBots, values),
+		ExpressionAttributeValues: values,
+	}
+	if domain.IsInferredSessionID(id) {
+		visitorID, firstEventID, err := domain.ParseInferredSessionID(id)
+		if err != nil {
+			return events, err
+		}
+		// the range keeps other items of the visitor, such as idempotency records, out of the events.
+		lo, hi, err := clickevent.SKRange(firstEventID, "")
+		if err != nil {
+			return events, err
+		}
+		values = map[string]types.AttributeValue{
+			":visitorId": &types.AttributeValueMemberS{Value: visitorID},
+			":lo":        &types.AttributeValueMemberS{Value: lo},
+			":hi":        &types.AttributeValueMemberS{Value: hi},
+		}
+		params = &dynamodb.QueryInput{
+			TableName:                 aws.String(TableName),
+			IndexName:                 aws.String(VisitorIndexName),
+			KeyConditionExpression:    aws.String("visitorId = :visitorId AND SK BETWEEN :lo AND :hi"),
+			FilterExpression:          clickevent.Filter("attribute_not_exists(sessionId)", includeBots, values),
+			ExpressionAttributeValues: values,
+		}
+	}
+
+	var last time.Time
+	paginator := dynamodb.NewQueryPaginator(client, params)
+	for paginator.HasMorePages() && len(events) < SessionMaxEvents {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			commoninstrument.SetParamsToSpanAttr(logger, span, params)
+			commoninstrument.RecordError(logger, span, err)
+			return events, err
+		}
+
+		var page []domain.ClickEvent
+		if err := clickevent.UnmarshalItems(output.Items, &page); err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			return events, err
+		}
+
+		for i := range page {
+			t := page[i].Time()
+			if !last.IsZero() && t.Sub(last) > gap && page[i].SessionID == "" {
+				return events, nil
+			}
+			last = t
+			events = append(events, page[i])
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/clickevent"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/domain"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/dto"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/instrument"
+	commoninstrument "github.com/haandol/vertical-slice-go-lambda-example/api/internal/instrument"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/o11y"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+// primary adapter.
+func ListSessionsController(c *gin.Context) {
+	logger := slogger.New().WithArgs(
+		"feature", "session",
+		"usecase", "listSessions",
+		"component", "controller",
+	)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ControllerTimeout)
+	defer cancel()
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ListSessionsController")
+	defer span.Close(nil)
+
+	var query dto.SessionListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": "invalid query",
+		})
+		return
+	}
+	if err := util.ValidateStruct(&query); err != nil {
+		commoninstrument.RecordBadInputError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"message": err.Error(),
+		})
+		return
+	}
+
+	sessions, truncated, err := ListSessionsService(ctx, &query)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidTimeRange) || errors.Is(err, ErrInvalidGap) {
+			commoninstrument.RecordBadInputError(logger, span, err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
+		commoninstrument.RecordError(logger, span, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"message": "failed to list sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":      domain.SessionsDTO(sessions),
+		"truncated": truncated,
+	})
+}
+
+// service.
+// The result is truncated when the visitor has more than SessionMaxEvents events in the range.
+func ListSessionsService(ctx context.Context, query *dto.SessionListQuery) ([]domain.Session, bool, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "session",
+		"usecase", "listSessions",
+		"component", "service",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ListSessionsService")
+	defer span.Close(nil)
+	commoninstrument.RecordRequest(logger, span, query)
+
+	gap, err := parseGap(query.Gap)
+	if err != nil {
+		return nil, false, err
+	}
+
+	lo, hi, err := clickevent.SKRange(query.From, query.To)
+	if err != nil {
+		return nil, false, err
+	}
+
+	events, err := ListSessionsRepository(ctx, query.VisitorID, lo, hi, query.IncludeBots)
+	if err != nil {
+		instrument.RecordListSessionsError(logger, span, err)
+		return nil, false, err
+	}
+
+	return sessionize(events, gap), len(events) >= SessionMaxEvents, nil
+}
+
+// secondary adapter.
+func ListSessionsRepository(ctx context.Context, visitorID, lo, hi string, includeBots bool) ([]domain.ClickEvent, error) {
+	logger := slogger.New().WithContext(ctx).WithArgs(
+		"feature", "session",
+		"usecase", "listSessions",
+		"component", "repository",
+	)
+
+	ctx, span := o11y.BeginSubSegment(ctx, "ListSessionsRepository")
+	defer span.Close(nil)
+
+	var events []domain.ClickEvent
+
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		commoninstrument.RecordError(logger, span, err)
+		return events, err
+	}
+
+	values := map[string]types.AttributeValue{
+		":visitorId": &types.AttributeValueMemberS{Value: visitorID},
+		":lo":        &types.AttributeValueMemberS{Value: lo},
+		":hi":        &types.AttributeValueMemberS{Value: hi},
+	}
+	params := &dynamodb.QueryInput{
+		TableName:                 aws.String(TableName),
+		IndexName:                 aws.String(VisitorIndexName),
+		KeyConditionExpression:    aws.String("visitorId = :visitorId AND SK BETWEEN :lo AND :hi"),
+		FilterExpression:          clickevent.Filter("", includeBots, values),
+		ExpressionAttributeValues: values,
+	}
+	paginator := dynamodb.NewQueryPaginator(client, params)
+	for paginator.HasMorePages() && len(events) < SessionMaxEvents {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			commoninstrument.SetParamsToSpanAttr(logger, span, params)
+			commoninstrument.RecordError(logger, span, err)
+			return events, err
+		}
+
+		var page []domain.ClickEvent
+		if err := clickevent.UnmarshalItems(output.Items, &page); err != nil {
+			commoninstrument.RecordError(logger, span, err)
+			return events, err
+		}
+		events = append(events, page...)
+	}
+
+	if len(events) > SessionMaxEvents {
+		events = events[:SessionMaxEvents]
+	}
+
+	return events, nil
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/haandol/vertical-slice-go-lambda-example/api/internal/feature/session/domain"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidGap = errors.New("invalid gap")
+
+// sessionize groups click events into sessions.
+// Events with a client session id belong to that session, the others are split per visitor
+// whenever two consecutive events are further apart than the inactivity gap.
+func sessionize(events []domain.ClickEvent, gap time.Duration) []domain.Session {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time().Before(events[j].Time())
+	})
+
+	sessions := make([]*domain.Session, 0)
+	clientSessions := make(map[string]*domain.Session)
+	openSessions := make(map[string]*domain.Session)
+
+	for i := range events {
+		event := &events[i]
+		t := event.Time()
+
+		var session *domain.Session
+		if event.SessionID != "" {
+			session = clientSessions[event.SessionID]
+			if session == nil {
+				session = newSession(event.SessionID, domain.SourceClient, event, t)
+				clientSessions[event.SessionID] = session
+				sessions = append(sessions, session)
+			}
+		} else {
+			session = openSessions[event.VisitorID]
+			if session == nil || t.Sub(session.End) > gap {
+				session = newSession(domain.InferredSessionID(event.VisitorID, event.ID), domain.SourceInferred, event, t)
+				openSessions[event.VisitorID] = session
+				sessions = append(sessions, session)
+			}
+		}
+
+		session.End = t
+		session.ExitPath = event.Path
+		session.Paths = append(session.Paths, event.Path)
+	}
+
+	result := make([]domain.Session, 0, len(sessions))
+	for _, session := range sessions {
+		result = append(result, *session)
+	}
+	return result
+}
+
+func newSession(id, source string, event *domain.ClickEvent, t time.Time) *domain.Session {
+	return &domain.Session{
+		ID:        id,
+		VisitorID: event.VisitorID,
+		Source:    source,
+		Start:     t,
+		End:       t,
+		EntryPath: event.Path,
+	}
+}
+
+func parseGap(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultInactivityGap, nil
+	}
+
+	gap, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrap(ErrInvalidGap, err.Error())
+	}
+	if gap < MinInactivityGap || gap > MaxInactivityGap {
+		return 0, errors.Wrapf(ErrInvalidGap, "gap must be between %v and %v", MinInactivityGap, MaxInactivityGap)
+	}
+
+	return gap, nil
+}
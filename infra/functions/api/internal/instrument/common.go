@@ -26,13 +26,15 @@ func RecordError(logger *slogger.Logger, span *xray.Segment, err error) {
 	}
 }
 
+// RecordRequest logs the JSON form of the request, so fields hidden from JSON, such as the
+// client IP, are never logged.
 func RecordRequest(logger *slogger.Logger, span *xray.Segment, request interface{}) {
-	logger.Info(fmt.Sprintf("request: %v", request))
 	value, err := json.Marshal(request)
 	if err != nil {
 		logger.Error("json marshal error", "err", err)
 		return
 	}
+	logger.Info(fmt.Sprintf("request: %s", value))
 
 	if span != nil {
 		_ = span.AddMetadata("request", string(value))
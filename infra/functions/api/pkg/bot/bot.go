@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"bufio"
+	_ "embed"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ReasonUserAgent        = "user-agent"
+	ReasonIPRange          = "ip-range"
+	ReasonMissingUserAgent = "missing-user-agent"
+	ReasonMissingHeaders   = "missing-headers"
+	ReasonRate             = "rate"
+)
+
+var (
+	//go:embed signatures.txt
+	signaturesFile string
+	//go:embed ranges.txt
+	rangesFile string
+)
+
+type Request struct {
+	IP        string
+	UserAgent string
+	Header    http.Header
+}
+
+type Verdict struct {
+	IsBot  bool
+	Reason string
+}
+
+type Options struct {
+	// ExtraRanges are CIDRs added to the embedded crawler ranges.
+	ExtraRanges []string
+	// RateLimit is the number of events one IP may send within RateWindow; zero disables the check.
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+// Classifier tells bots from humans with a user-agent signature list, known crawler ranges
+// and heuristics. The rate heuristic is counted per process, so it is only a rough guard.
+type Classifier struct {
+	signatures []string
+	ranges     []netip.Prefix
+	rate       *rateCounter
+}
+
+func NewClassifier(opts Options) (*Classifier, error) {
+	ranges := make([]netip.Prefix, 0)
+	for _, cidr := range append(lines(rangesFile), opts.ExtraRanges...) {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid bot ip range %v", cidr)
+		}
+		ranges = append(ranges, prefix.Masked())
+	}
+
+	c := &Classifier{
+		signatures: lines(signaturesFile),
+		ranges:     ranges,
+	}
+	if opts.RateLimit > 0 && opts.RateWindow > 0 {
+		c.rate = newRateCounter(opts.RateLimit, opts.RateWindow)
+	}
+	return c, nil
+}
+
+// Classify checks the request in order of confidence and returns the first reason that matches.
+// n is the number of events the request carries, used by the rate heuristic.
+func (c *Classifier) Classify(req *Request, n int) Verdict {
+	ua := strings.ToLower(strings.TrimSpace(req.UserAgent))
+	if ua == "" {
+		return Verdict{IsBot: true, Reason: ReasonMissingUserAgent}
+	}
+	for _, signature := range c.signatures {
+		if strings.Contains(ua, signature) {
+			return Verdict{IsBot: true, Reason: ReasonUserAgent}
+		}
+	}
+
+	addr, err := netip.ParseAddr(req.IP)
+	if err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range c.ranges {
+			if prefix.Contains(addr) {
+				return Verdict{IsBot: true, Reason: ReasonIPRange}
+			}
+		}
+	}
+
+	// browsers always send both, while most scripts send neither.
+	if req.Header != nil && req.Header.Get("Accept") == "" && req.Header.Get("Accept-Language") == "" {
+		return Verdict{IsBot: true, Reason: ReasonMissingHeaders}
+	}
+
+	if c.rate != nil && req.IP != "" && !c.rate.Allow(req.IP, n, time.Now()) {
+		return Verdict{IsBot: true, Reason: ReasonRate}
+	}
+
+	return Verdict{}
+}
+
+func lines(s string) []string {
+	result := make([]string, 0)
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, strings.ToLower(line))
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+// rateCounter counts events per key in fixed windows.
+type rateCounter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	start  time.Time
+	counts map[string]int
+}
+
+func newRateCounter(limit int, window time.Duration) *rateCounter {
+	return &rateCounter{
+		limit:  limit,
+		window: window,
+		counts: make(map[string]int),
+	}
+}
+
+// Allow adds n events for the key and reports whether the key is still within the limit.
+func (r *rateCounter) Allow(key string, n int, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.start) >= r.window {
+		// dropping every key at once keeps the map bounded.
+		r.start = now
+		r.counts = make(map[string]int)
+	}
+
+	r.counts[key] += n
+	return r.counts[key] <= r.limit
+}
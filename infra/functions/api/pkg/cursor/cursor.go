@@ -0,0 +1,135 @@
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util"
+	"github.com/pkg/errors"
+)
+
+const (
+	localSecret = "local-cursor-secret"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrMissingSecret = errors.New("CURSOR_SECRET is not set")
+
+	CURSOR_SECRET = os.Getenv("CURSOR_SECRET")
+)
+
+type attr struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+}
+
+// Encode turns a DynamoDB LastEvaluatedKey into an opaque token signed with HMAC-SHA256.
+// The scope is mixed into the signature so a token issued for one query can not be replayed on another.
+func Encode(scope string, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	secret, err := getSecret()
+	if err != nil {
+		return "", err
+	}
+
+	payload := make(map[string]attr, len(key))
+	for k, v := range key {
+		switch av := v.(type) {
+		case *types.AttributeValueMemberS:
+			payload[k] = attr{S: &av.Value}
+		case *types.AttributeValueMemberN:
+			payload[k] = attr{N: &av.Value}
+		default:
+			return "", errors.Errorf("unsupported key attribute type for %v", k)
+		}
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	body := base64.RawURLEncoding.EncodeToString(b)
+	sig := base64.RawURLEncoding.EncodeToString(sign(secret, scope, body))
+
+	return body + "." + sig, nil
+}
+
+// Decode verifies the token against the scope and returns the ExclusiveStartKey it carries.
+// An empty token decodes to a nil key, which means "first page".
+func Decode(scope, token string) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	secret, err := getSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	body, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !hmac.Equal(sig, sign(secret, scope, body)) {
+		return nil, ErrInvalidCursor
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload map[string]attr
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(payload))
+	for k, v := range payload {
+		switch {
+		case v.S != nil:
+			key[k] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil:
+			key[k] = &types.AttributeValueMemberN{Value: *v.N}
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return key, nil
+}
+
+func sign(secret []byte, scope, body string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+func getSecret() ([]byte, error) {
+	if CURSOR_SECRET != "" {
+		return []byte(CURSOR_SECRET), nil
+	}
+
+	if util.IsLocalEnv() {
+		return []byte(localSecret), nil
+	}
+
+	return nil, ErrMissingSecret
+}
@@ -0,0 +1,70 @@
+package geoip
+
+import (
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidIP = errors.New("invalid ip")
+
+type Location struct {
+	Country string
+	Region  string
+	City    string
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// DB looks up locations in a MaxMind-format database. It is safe for concurrent use.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+// Open memory-maps the database file, so it is read lazily by the OS instead of loaded into the heap.
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open geoip database %v", path)
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Lookup returns the ISO country code, the ISO code of the first subdivision and the English city name.
+// An address that is not in the database yields an empty Location.
+func (d *DB) Lookup(ip string) (Location, error) {
+	var location Location
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return location, ErrInvalidIP
+	}
+
+	var r record
+	if err := d.reader.Lookup(addr, &r); err != nil {
+		return location, err
+	}
+
+	location.Country = r.Country.ISOCode
+	if len(r.Subdivisions) > 0 {
+		location.Region = r.Subdivisions[0].ISOCode
+	}
+	location.City = r.City.Names["en"]
+
+	return location, nil
+}
+
+func (d *DB) Close() error {
+	return d.reader.Close()
+}
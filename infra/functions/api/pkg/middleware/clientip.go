@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	trustedHopsOnce sync.Once
+	trustedHops     int
+)
+
+// ClientIP returns the client IP from X-Forwarded-For, falling back to the remote address.
+// Proxies append to the header, so entries on the left can be forged by the client.
+// The IP is read from the right, skipping TRUSTED_PROXY_HOPS proxies in front of the
+// API Gateway, such as a CDN.
+func ClientIP(c *gin.Context) string {
+	trustedHopsOnce.Do(func() {
+		trustedHops, _ = strconv.Atoi(os.Getenv("TRUSTED_PROXY_HOPS"))
+	})
+
+	entries := make([]string, 0)
+	for _, header := range c.Request.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+	if i := len(entries) - 1 - trustedHops; i >= 0 && i < len(entries) {
+		if addr, err := netip.ParseAddr(entries[i]); err == nil {
+			return addr.Unmap().String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+	if err != nil {
+		host = strings.TrimSpace(c.Request.RemoteAddr)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String()
+	}
+	return ""
+}
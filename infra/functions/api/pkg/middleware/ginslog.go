@@ -49,13 +49,13 @@ func GinSlogWithConfig(logger *slogger.Logger, conf *Config) gin.HandlerFunc {
 					logger.Error(e)
 				}
 			} else {
+				// the client ip is left out, raw ips are never logged.
 				fields := []any{
 					slog.Int("status", c.Writer.Status()),
 					slog.String("request-id", c.Request.Header.Get("X-Request-ID")),
 					slog.String("method", c.Request.Method),
 					slog.String("path", path),
 					slog.String("query", query),
-					slog.String("ip", c.ClientIP()),
 					slog.String("user-agent", c.Request.UserAgent()),
 					slog.Duration("latency", latency),
 					slog.String("time", end.Format(time.RFC3339)),
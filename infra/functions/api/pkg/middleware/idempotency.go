@@ -0,0 +1,353 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gin-gonic/gin"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/util/slogger"
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotency-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	idempotencySubjectKey   = "idempotencySubject"
+)
+
+// ErrIdempotencyLeaseLost is returned when the record of a request was taken over by a retry
+// after its lease ran out, so the request no longer owns the key.
+var ErrIdempotencyLeaseLost = errors.New("idempotency lease lost")
+
+type IdempotencyRecord struct {
+	Key         string `dynamodbav:"key"`
+	RequestHash string `dynamodbav:"requestHash"`
+	// LeaseID tells the request that reserved the key apart from a retry that took it over.
+	LeaseID     string `dynamodbav:"leaseId"`
+	Status      int    `dynamodbav:"status"`
+	ContentType string `dynamodbav:"contentType,omitempty"`
+	Body        []byte `dynamodbav:"body,omitempty"`
+	ExpiresAt   int64  `dynamodbav:"expiresAt"`
+	// Subject identifies the person whose data is in the stored response, so the record can be erased with it.
+	Subject string `dynamodbav:"-"`
+}
+
+// Completed reports whether the original request has finished; a zero status means it is still in flight.
+func (r *IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
+
+type IdempotencyStore interface {
+	// Reserve stores an in-flight record for the key.
+	// It returns the existing record instead when the key is already taken and its record has not expired.
+	Reserve(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	// Complete and Release only apply while the in-flight record is still the one reserved,
+	// and return ErrIdempotencyLeaseLost otherwise.
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	Release(ctx context.Context, record *IdempotencyRecord) error
+}
+
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// SetIdempotencySubject tells the Idempotency middleware whose personal data the response holds.
+func SetIdempotencySubject(c *gin.Context, subject string) {
+	c.Set(idempotencySubjectKey, subject)
+}
+
+// Idempotency replays the stored response when a request is retried with the same Idempotency-Key.
+// Keys are scoped by method and path, so clients that pick the same key for different requests do not meet.
+// Reusing a key with a different payload is rejected with 422, and server errors and panics release the key
+// so the client can retry. An in-flight key is only held for the lease, so a request that was killed
+// before it could release its key blocks retries for no longer than that; the stored response is kept for ttl.
+func Idempotency(logger *slogger.Logger, store IdempotencyStore, ttl, lease time.Duration, maxBodySize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "idempotency key is too long",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"message": "body is too large",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"message": "invalid body",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		leaseID, err := newLeaseID()
+		if err != nil {
+			logger.Error("failed to create idempotency lease", "err", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"message": "idempotency store unavailable",
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		record := &IdempotencyRecord{
+			Key:         scopedIdempotencyKey(c.Request.Method, c.Request.URL.Path, key),
+			RequestHash: requestHash(c.Request.Method, c.Request.URL.Path, body),
+			LeaseID:     leaseID,
+			ExpiresAt:   time.Now().Add(lease).Unix(),
+		}
+
+		existing, err := store.Reserve(ctx, record)
+		if err != nil {
+			logger.Error("failed to reserve idempotency key", "err", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"message": "idempotency store unavailable",
+			})
+			return
+		}
+		if existing != nil {
+			replay(c, record, existing)
+			return
+		}
+
+		// the recovery middleware sits outside, so a panic would otherwise leave the key held until the lease ends.
+		defer func() {
+			if r := recover(); r != nil {
+				if err := store.Release(context.WithoutCancel(ctx), record); err != nil {
+					logger.Error("failed to release idempotency key", "err", err)
+				}
+				panic(r)
+			}
+		}()
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+
+		record.Status = c.Writer.Status()
+		if record.Status >= http.StatusInternalServerError {
+			if err := store.Release(ctx, record); err != nil {
+				logger.Error("failed to release idempotency key", "err", err)
+			}
+			return
+		}
+
+		record.ContentType = c.Writer.Header().Get("Content-Type")
+		record.Body = writer.body.Bytes()
+		record.Subject = c.GetString(idempotencySubjectKey)
+		record.ExpiresAt = time.Now().Add(ttl).Unix()
+		if err := store.Complete(ctx, record); err != nil {
+			if errors.Is(err, ErrIdempotencyLeaseLost) {
+				logger.Warn("idempotency key was taken over by a retry", "key", record.Key)
+				return
+			}
+			logger.Error("failed to complete idempotency key", "err", err)
+		}
+	}
+}
+
+func replay(c *gin.Context, record, existing *IdempotencyRecord) {
+	switch {
+	case existing.RequestHash != record.RequestHash:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "idempotency key is already used for a different request",
+		})
+	case !existing.Completed():
+		c.Header("Retry-After", strconv.FormatInt(max(existing.ExpiresAt-time.Now().Unix(), 1), 10))
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+			"message": "request with the same idempotency key is in progress",
+		})
+	default:
+		c.Header(HeaderIdempotencyReplayed, "true")
+		c.Data(existing.Status, existing.ContentType, existing.Body)
+		c.Abort()
+	}
+}
+
+func scopedIdempotencyKey(method, path, key string) string {
+	return method + " " + path + "#" + key
+}
+
+func newLeaseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type DynamoDBIdempotencyStore struct {
+	tableName   string
+	subjectAttr string
+}
+
+// NewDynamoDBIdempotencyStore stores the subject of a record under subjectAttr,
+// so it lands on the index the subject's data is erased from.
+func NewDynamoDBIdempotencyStore(tableName, subjectAttr string) *DynamoDBIdempotencyStore {
+	return &DynamoDBIdempotencyStore{tableName: tableName, subjectAttr: subjectAttr}
+}
+
+func (s *DynamoDBIdempotencyStore) Reserve(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.item(record)
+	if err != nil {
+		return nil, err
+	}
+
+	// a record that is expired but not yet swept by TTL, or an in-flight record past its lease, can be taken over.
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK) OR expiresAt < :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)},
+		},
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		return nil, nil
+	}
+
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return nil, err
+	}
+
+	var existing IdempotencyRecord
+	if err := attributevalue.UnmarshalMap(ccf.Item, &existing); err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (s *DynamoDBIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	item, err := s.item(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                 aws.String(s.tableName),
+		Item:                      item,
+		ConditionExpression:       aws.String(leaseCondition),
+		ExpressionAttributeNames:  leaseNames(),
+		ExpressionAttributeValues: leaseValues(record),
+	})
+	return leaseError(err)
+}
+
+func (s *DynamoDBIdempotencyStore) Release(ctx context.Context, record *IdempotencyRecord) error {
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                 aws.String(s.tableName),
+		Key:                       s.key(record.Key),
+		ConditionExpression:       aws.String(leaseCondition),
+		ExpressionAttributeNames:  leaseNames(),
+		ExpressionAttributeValues: leaseValues(record),
+	})
+	return leaseError(err)
+}
+
+// leaseCondition holds while the stored record is the in-flight one of the request,
+// not one a retry put in its place after the lease ran out.
+const leaseCondition = "requestHash = :hash AND leaseId = :lease AND #status = :inflight"
+
+func leaseNames() map[string]string {
+	return map[string]string{"#status": "status"}
+}
+
+func leaseValues(record *IdempotencyRecord) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		":hash":     &types.AttributeValueMemberS{Value: record.RequestHash},
+		":lease":    &types.AttributeValueMemberS{Value: record.LeaseID},
+		":inflight": &types.AttributeValueMemberN{Value: "0"},
+	}
+}
+
+func leaseError(err error) error {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return ErrIdempotencyLeaseLost
+	}
+	return err
+}
+
+func (s *DynamoDBIdempotencyStore) key(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "IDEMPOTENCY#" + key},
+		"SK": &types.AttributeValueMemberS{Value: "IDEMPOTENCY"},
+	}
+}
+
+func (s *DynamoDBIdempotencyStore) item(record *IdempotencyRecord) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range s.key(record.Key) {
+		item[k] = v
+	}
+	if record.Subject != "" && s.subjectAttr != "" {
+		item[s.subjectAttr] = &types.AttributeValueMemberS{Value: record.Subject}
+	}
+	return item, nil
+}
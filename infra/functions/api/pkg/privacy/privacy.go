@@ -0,0 +1,70 @@
+package privacy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// FingerprintPrefix marks visitor ids derived by the server, so they are never mistaken for client ids.
+const FingerprintPrefix = "fp."
+
+type SaltStore interface {
+	// Salt returns the salt of the period starting at start, creating it when it does not exist yet.
+	// Creating a salt destroys the salts of earlier periods.
+	Salt(ctx context.Context, start time.Time, ttl time.Duration) ([]byte, error)
+}
+
+// Fingerprinter derives visitor ids as HMAC(salt, ip + user-agent).
+// The salt changes every period, so a fingerprint can not be linked across periods
+// once the old salt is destroyed. The stored salt is destroyed as soon as the next one is created;
+// an instance keeps its copy in memory until its first fingerprint in the next period replaces it.
+type Fingerprinter struct {
+	store  SaltStore
+	period time.Duration
+
+	mu    sync.Mutex
+	start time.Time
+	salt  []byte
+}
+
+func NewFingerprinter(store SaltStore, period time.Duration) *Fingerprinter {
+	return &Fingerprinter{store: store, period: period}
+}
+
+func (f *Fingerprinter) Fingerprint(ctx context.Context, ip, userAgent string, now time.Time) (string, error) {
+	salt, err := f.currentSalt(ctx, now)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return FingerprintPrefix + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// currentSalt keeps only the salt of the current period in memory.
+func (f *Fingerprinter) currentSalt(ctx context.Context, now time.Time) ([]byte, error) {
+	start := now.UTC().Truncate(f.period)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.salt != nil && f.start.Equal(start) {
+		return f.salt, nil
+	}
+
+	// only the salt of the current period is ever read, so it may expire with its period;
+	// TTL only matters when no salt is created after it.
+	salt, err := f.store.Salt(ctx, start, f.period)
+	if err != nil {
+		return nil, err
+	}
+	f.start, f.salt = start, salt
+	return salt, nil
+}
@@ -0,0 +1,106 @@
+package privacy
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/haandol/vertical-slice-go-lambda-example/api/pkg/connector/cloud"
+	"github.com/pkg/errors"
+)
+
+const (
+	saltPK     = "PRIVACY#SALT"
+	saltPrefix = "SALT#"
+	saltSize   = 32
+)
+
+// DynamoDBSaltStore keeps one item per period, so every instance derives the same fingerprints.
+// Old salts are deleted by the instance that creates the next one, and by TTL as a fallback.
+type DynamoDBSaltStore struct {
+	tableName string
+}
+
+func NewDynamoDBSaltStore(tableName string) *DynamoDBSaltStore {
+	return &DynamoDBSaltStore{tableName: tableName}
+}
+
+func (s *DynamoDBSaltStore) Salt(ctx context.Context, start time.Time, ttl time.Duration) ([]byte, error) {
+	client, err := cloud.NewDynamoDBClient()
+	if err != nil {
+		return nil, err
+	}
+
+	sk := saltPrefix + start.UTC().Format(time.RFC3339)
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]types.AttributeValue{
+			"PK":        &types.AttributeValueMemberS{Value: saltPK},
+			"SK":        &types.AttributeValueMemberS{Value: sk},
+			"salt":      &types.AttributeValueMemberB{Value: salt},
+			"expiresAt": &types.AttributeValueMemberN{Value: strconv.FormatInt(start.Add(ttl).Unix(), 10)},
+		},
+		ConditionExpression:                 aws.String("attribute_not_exists(PK)"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	if err == nil {
+		if err := s.destroyBefore(ctx, client, sk); err != nil {
+			return nil, err
+		}
+		return salt, nil
+	}
+
+	// another instance created it first.
+	var ccf *types.ConditionalCheckFailedException
+	if !errors.As(err, &ccf) {
+		return nil, err
+	}
+	existing, ok := ccf.Item["salt"].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, errors.Errorf("salt item %v has no salt", sk)
+	}
+	return existing.Value, nil
+}
+
+func (s *DynamoDBSaltStore) destroyBefore(ctx context.Context, client *dynamodb.Client, sk string) error {
+	paginator := dynamodb.NewQueryPaginator(client, &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :lo AND :hi"),
+		ProjectionExpression:   aws.String("PK, SK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: saltPK},
+			":lo": &types.AttributeValueMemberS{Value: saltPrefix},
+			":hi": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, item := range output.Items {
+			if v, ok := item["SK"].(*types.AttributeValueMemberS); ok && v.Value == sk {
+				continue
+			}
+			_, err := client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName: aws.String(s.tableName),
+				Key:       map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]},
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package pubsub
+
+// Hub fans out messages published on a topic to its subscribers.
+// Publish never blocks; a subscriber that cannot keep up is dropped.
+type Hub interface {
+	Publish(topic string, msg any)
+	Subscribe(topic string) *Subscription
+}
+
+// Subscription delivers messages on C until Close is called or the hub drops it.
+// C is closed in both cases; Dropped tells them apart.
+type Subscription struct {
+	C <-chan any
+
+	ch      chan any
+	dropped bool
+	cancel  func()
+}
+
+func (s *Subscription) Close() {
+	s.cancel()
+}
+
+// Dropped reports whether the hub closed the subscription because its buffer was full.
+// It is only meaningful after C is closed.
+func (s *Subscription) Dropped() bool {
+	return s.dropped
+}
@@ -0,0 +1,70 @@
+package pubsub
+
+import "sync"
+
+// MemoryHub is an in-process Hub. Messages are only delivered to subscribers
+// of the same process, which suits the local server.
+type MemoryHub struct {
+	mu     sync.Mutex
+	buffer int
+	topics map[string]map[*Subscription]struct{}
+}
+
+// NewMemoryHub creates a hub that buffers up to buffer messages per subscriber.
+func NewMemoryHub(buffer int) *MemoryHub {
+	return &MemoryHub{
+		buffer: buffer,
+		topics: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+func (h *MemoryHub) Publish(topic string, msg any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.dropped = true
+			h.remove(topic, sub)
+		}
+	}
+}
+
+func (h *MemoryHub) Subscribe(topic string) *Subscription {
+	ch := make(chan any, h.buffer)
+	sub := &Subscription{C: ch, ch: ch}
+	sub.cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(topic, sub)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+
+	return sub
+}
+
+// remove closes the subscription once; it must be called with the lock held.
+func (h *MemoryHub) remove(topic string, sub *Subscription) {
+	subs, ok := h.topics[topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	close(sub.ch)
+	if len(subs) == 0 {
+		delete(h.topics, topic)
+	}
+}
@@ -0,0 +1,188 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	MinPrecision     = 4
+	MaxPrecision     = 16
+	DefaultPrecision = 12
+
+	hllVersion   = 1
+	hllDense     = 1
+	hllSparse    = 2
+	hllHeaderLen = 3
+)
+
+var (
+	ErrPrecisionMismatch = errors.New("precision mismatch")
+	ErrInvalidSketch     = errors.New("invalid sketch")
+)
+
+// HyperLogLog estimates the number of distinct items it has seen in m = 2^p registers.
+// Sketches of the same precision merge losslessly, so hourly sketches add up to daily ones.
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+func NewHyperLogLog(precision uint8) (*HyperLogLog, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, errors.Errorf("precision %d is out of [%d, %d]", precision, MinPrecision, MaxPrecision)
+	}
+	return &HyperLogLog{p: precision, registers: make([]uint8, 1<<precision)}, nil
+}
+
+func (h *HyperLogLog) Precision() uint8 {
+	return h.p
+}
+
+// Add counts the item and reports whether the sketch changed.
+// Most adds of a populated sketch change nothing, which lets callers skip the write.
+func (h *HyperLogLog) Add(item string) bool {
+	x := hash64(item)
+	idx := x >> (64 - h.p)
+	rank := uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	if rank <= h.registers[idx] {
+		return false
+	}
+	h.registers[idx] = rank
+	return true
+}
+
+// Merge folds other into the sketch and reports whether the sketch changed.
+func (h *HyperLogLog) Merge(other *HyperLogLog) (bool, error) {
+	if other.p != h.p {
+		return false, errors.Wrapf(ErrPrecisionMismatch, "%d != %d", h.p, other.p)
+	}
+	changed := false
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// Estimate returns the approximate number of distinct items,
+// using linear counting while many registers are still empty.
+func (h *HyperLogLog) Estimate() uint64 {
+	m := float64(len(h.registers))
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(len(h.registers)) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// StandardError returns the relative standard error of the estimate, 1.04/sqrt(m).
+func (h *HyperLogLog) StandardError() float64 {
+	return 1.04 / math.Sqrt(float64(len(h.registers)))
+}
+
+// MarshalBinary writes a version and precision header followed by the registers,
+// either densely or as (index, value) pairs when that is shorter.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	nonzero := 0
+	for _, r := range h.registers {
+		if r != 0 {
+			nonzero++
+		}
+	}
+
+	if nonzero*3 >= len(h.registers) {
+		buf := make([]byte, hllHeaderLen, hllHeaderLen+len(h.registers))
+		buf[0], buf[1], buf[2] = hllVersion, hllDense, h.p
+		return append(buf, h.registers...), nil
+	}
+
+	buf := make([]byte, hllHeaderLen, hllHeaderLen+nonzero*3)
+	buf[0], buf[1], buf[2] = hllVersion, hllSparse, h.p
+	for i, r := range h.registers {
+		if r != 0 {
+			buf = binary.BigEndian.AppendUint16(buf, uint16(i))
+			buf = append(buf, r)
+		}
+	}
+	return buf, nil
+}
+
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < hllHeaderLen || data[0] != hllVersion {
+		return errors.Wrap(ErrInvalidSketch, "unknown header")
+	}
+
+	sketch, err := NewHyperLogLog(data[2])
+	if err != nil {
+		return errors.Wrap(ErrInvalidSketch, err.Error())
+	}
+
+	body := data[hllHeaderLen:]
+	switch data[1] {
+	case hllDense:
+		if len(body) != len(sketch.registers) {
+			return errors.Wrap(ErrInvalidSketch, "truncated registers")
+		}
+		copy(sketch.registers, body)
+	case hllSparse:
+		if len(body)%3 != 0 {
+			return errors.Wrap(ErrInvalidSketch, "truncated registers")
+		}
+		for i := 0; i < len(body); i += 3 {
+			idx := int(binary.BigEndian.Uint16(body[i:]))
+			if idx >= len(sketch.registers) {
+				return errors.Wrap(ErrInvalidSketch, "register out of range")
+			}
+			sketch.registers[idx] = body[i+2]
+		}
+	default:
+		return errors.Wrap(ErrInvalidSketch, "unknown encoding")
+	}
+
+	*h = *sketch
+	return nil
+}
+
+func alpha(m int) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/float64(m))
+	}
+}
+
+// hash64 spreads FNV-1a with the murmur3 finalizer, since the registers
+// need well mixed high bits and the hash must be stable across processes.
+func hash64(item string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(item))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,155 @@
+package sketch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func newTestHLL(t *testing.T, precision uint8, from, to int) *HyperLogLog {
+	t.Helper()
+	h, err := NewHyperLogLog(precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := from; i < to; i++ {
+		h.Add(fmt.Sprintf("visitor-%d", i))
+	}
+	return h
+}
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	tests := []struct {
+		precision uint8
+		n         int
+	}{
+		{precision: 12, n: 0},
+		{precision: 12, n: 1},
+		{precision: 12, n: 100},
+		{precision: 12, n: 10000},
+		{precision: 12, n: 200000},
+		{precision: 4, n: 1000},
+		{precision: 16, n: 100000},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("p%d/n%d", tt.precision, tt.n), func(t *testing.T) {
+			h := newTestHLL(t, tt.precision, 0, tt.n)
+
+			// 4 standard errors keep the deterministic hash well inside the bound.
+			bound := 4*h.StandardError()*float64(tt.n) + 1
+			if diff := math.Abs(float64(h.Estimate()) - float64(tt.n)); diff > bound {
+				t.Errorf("estimate %d of %d is off by %v, more than %v", h.Estimate(), tt.n, diff, bound)
+			}
+		})
+	}
+}
+
+func TestHyperLogLogAddReportsChange(t *testing.T) {
+	h := newTestHLL(t, DefaultPrecision, 0, 0)
+	if !h.Add("a") {
+		t.Error("first add did not change the sketch")
+	}
+	if h.Add("a") {
+		t.Error("repeated add changed the sketch")
+	}
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	a := newTestHLL(t, DefaultPrecision, 0, 6000)
+	b := newTestHLL(t, DefaultPrecision, 4000, 10000)
+	union := newTestHLL(t, DefaultPrecision, 0, 10000)
+
+	ab := newTestHLL(t, DefaultPrecision, 0, 0)
+	for _, h := range []*HyperLogLog{a, b} {
+		if _, err := ab.Merge(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ba := newTestHLL(t, DefaultPrecision, 0, 0)
+	for _, h := range []*HyperLogLog{b, a} {
+		if _, err := ba.Merge(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !bytes.Equal(ab.registers, ba.registers) {
+		t.Error("merge is not commutative")
+	}
+	if !bytes.Equal(ab.registers, union.registers) {
+		t.Error("merged sketch differs from the sketch of the union")
+	}
+
+	changed, err := ab.Merge(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Error("merging a contained sketch reported a change")
+	}
+}
+
+func TestHyperLogLogMergePrecisionMismatch(t *testing.T) {
+	a := newTestHLL(t, 10, 0, 10)
+	b := newTestHLL(t, 12, 0, 10)
+	if _, err := a.Merge(b); !errors.Is(err, ErrPrecisionMismatch) {
+		t.Errorf("got %v, want ErrPrecisionMismatch", err)
+	}
+}
+
+func TestHyperLogLogMarshalRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        int
+		encoding byte
+	}{
+		{name: "empty", n: 0, encoding: hllSparse},
+		{name: "sparse", n: 50, encoding: hllSparse},
+		{name: "dense", n: 50000, encoding: hllDense},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHLL(t, DefaultPrecision, 0, tt.n)
+
+			data, err := h.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data[1] != tt.encoding {
+				t.Errorf("encoding %d, want %d", data[1], tt.encoding)
+			}
+
+			var decoded HyperLogLog
+			if err := decoded.UnmarshalBinary(data); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Precision() != h.Precision() || !bytes.Equal(decoded.registers, h.registers) {
+				t.Error("decoded sketch differs from the original")
+			}
+		})
+	}
+}
+
+func TestHyperLogLogUnmarshalInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "short", data: []byte{hllVersion}},
+		{name: "version", data: []byte{9, hllDense, DefaultPrecision}},
+		{name: "precision", data: []byte{hllVersion, hllDense, 30}},
+		{name: "encoding", data: []byte{hllVersion, 9, 4}},
+		{name: "truncated dense", data: []byte{hllVersion, hllDense, 4, 1, 2}},
+		{name: "truncated sparse", data: []byte{hllVersion, hllSparse, 4, 0, 1}},
+		{name: "sparse out of range", data: []byte{hllVersion, hllSparse, 4, 0, 16, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h HyperLogLog
+			if err := h.UnmarshalBinary(tt.data); !errors.Is(err, ErrInvalidSketch) {
+				t.Errorf("got %v, want ErrInvalidSketch", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultCompression = 100
+
+	tdigestVersion   = 1
+	tdigestHeaderLen = 1 + 8*3
+	centroidLen      = 8 * 2
+)
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// TDigest approximates the distribution of the values it has seen with a bounded set of centroids,
+// keeping the tails precise enough for p99 while using a few kilobytes.
+// Digests merge, so hourly digests add up to daily ones.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min         float64
+	max         float64
+}
+
+func NewTDigest(compression float64) (*TDigest, error) {
+	if compression < 10 {
+		return nil, errors.Errorf("compression %v is below 10", compression)
+	}
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}, nil
+}
+
+func (t *TDigest) Count() float64 {
+	return t.count + t.buffered()
+}
+
+func (t *TDigest) Add(x float64) {
+	t.add(centroid{mean: x, weight: 1})
+}
+
+// Merge folds the centroids of other into the digest.
+func (t *TDigest) Merge(other *TDigest) {
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+	for _, c := range other.centroids {
+		t.add(c)
+	}
+	for _, c := range other.buffer {
+		t.add(c)
+	}
+}
+
+func (t *TDigest) add(c centroid) {
+	if math.IsNaN(c.mean) || c.weight <= 0 {
+		return
+	}
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+	t.buffer = append(t.buffer, c)
+	if len(t.buffer) >= int(t.compression)*5 {
+		t.compress()
+	}
+}
+
+// compress merges neighbouring centroids as long as a centroid spans at most one unit of the
+// arcsine scale function, which keeps the centroids near the tails small.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	total := 0.0
+	for _, c := range all {
+		total += c.weight
+	}
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	current := all[0]
+	soFar := 0.0
+	kLow := t.scale(0)
+	for _, next := range all[1:] {
+		if t.scale((soFar+current.weight+next.weight)/total)-kLow <= 1 {
+			weight := current.weight + next.weight
+			current.mean += (next.mean - current.mean) * next.weight / weight
+			current.weight = weight
+			continue
+		}
+		soFar += current.weight
+		kLow = t.scale(soFar / total)
+		merged = append(merged, current)
+		current = next
+	}
+	merged = append(merged, current)
+
+	t.centroids = merged
+	t.buffer = nil
+	t.count = total
+}
+
+func (t *TDigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*math.Min(1, q)-1)
+}
+
+func (t *TDigest) buffered() float64 {
+	weight := 0.0
+	for _, c := range t.buffer {
+		weight += c.weight
+	}
+	return weight
+}
+
+// Quantile returns the approximate value below which the fraction q of the values fall,
+// interpolating between centroid centers, and NaN for an empty digest.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+
+	n := len(t.centroids)
+	if n == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+	if n == 1 {
+		return t.centroids[0].mean
+	}
+
+	index := q * t.count
+	first := t.centroids[0]
+	if index < first.weight/2 {
+		return t.min + (first.mean-t.min)*index/(first.weight/2)
+	}
+
+	cumulative := first.weight / 2
+	for i := 0; i < n-1; i++ {
+		span := (t.centroids[i].weight + t.centroids[i+1].weight) / 2
+		if index < cumulative+span {
+			return t.centroids[i].mean + (index-cumulative)/span*(t.centroids[i+1].mean-t.centroids[i].mean)
+		}
+		cumulative += span
+	}
+
+	last := t.centroids[n-1]
+	return last.mean + (index-cumulative)/(last.weight/2)*(t.max-last.mean)
+}
+
+// MarshalBinary writes a version byte, the compression, min and max, then the (mean, weight) centroids.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	t.compress()
+
+	buf := make([]byte, 0, tdigestHeaderLen+len(t.centroids)*centroidLen)
+	buf = append(buf, tdigestVersion)
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(t.compression))
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(t.min))
+	buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(t.max))
+	for _, c := range t.centroids {
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(c.mean))
+		buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(c.weight))
+	}
+	return buf, nil
+}
+
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	if len(data) < tdigestHeaderLen || data[0] != tdigestVersion {
+		return errors.Wrap(ErrInvalidSketch, "unknown header")
+	}
+	if (len(data)-tdigestHeaderLen)%centroidLen != 0 {
+		return errors.Wrap(ErrInvalidSketch, "truncated centroids")
+	}
+
+	digest, err := NewTDigest(math.Float64frombits(binary.BigEndian.Uint64(data[1:])))
+	if err != nil {
+		return errors.Wrap(ErrInvalidSketch, err.Error())
+	}
+	digest.min = math.Float64frombits(binary.BigEndian.Uint64(data[9:]))
+	digest.max = math.Float64frombits(binary.BigEndian.Uint64(data[17:]))
+
+	body := data[tdigestHeaderLen:]
+	digest.centroids = make([]centroid, 0, len(body)/centroidLen)
+	for i := 0; i < len(body); i += centroidLen {
+		c := centroid{
+			mean:   math.Float64frombits(binary.BigEndian.Uint64(body[i:])),
+			weight: math.Float64frombits(binary.BigEndian.Uint64(body[i+8:])),
+		}
+		if math.IsNaN(c.mean) || c.weight <= 0 {
+			return errors.Wrap(ErrInvalidSketch, "invalid centroid")
+		}
+		digest.centroids = append(digest.centroids, c)
+		digest.count += c.weight
+	}
+
+	*t = *digest
+	return nil
+}
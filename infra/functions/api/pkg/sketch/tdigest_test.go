@@ -0,0 +1,198 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func newTestTDigest(t *testing.T, values []float64) *TDigest {
+	t.Helper()
+	d, err := NewTDigest(DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range values {
+		d.Add(v)
+	}
+	return d
+}
+
+// rankError returns how far the estimate is from q, measured in the rank of the sorted values.
+func rankError(sorted []float64, q, estimate float64) float64 {
+	rank := sort.SearchFloat64s(sorted, estimate)
+	return math.Abs(float64(rank)/float64(len(sorted)) - q)
+}
+
+func TestTDigestQuantile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 100000
+
+	distributions := map[string]func() float64{
+		"uniform":     func() float64 { return r.Float64() * 1000 },
+		"normal":      func() float64 { return r.NormFloat64()*100 + 500 },
+		"exponential": func() float64 { return r.ExpFloat64() * 250 },
+	}
+	quantiles := []struct {
+		q       float64
+		maxRank float64
+	}{
+		{q: 0.01, maxRank: 0.002},
+		{q: 0.5, maxRank: 0.01},
+		{q: 0.9, maxRank: 0.005},
+		{q: 0.99, maxRank: 0.002},
+		{q: 0.999, maxRank: 0.0005},
+	}
+
+	for name, next := range distributions {
+		t.Run(name, func(t *testing.T) {
+			values := make([]float64, n)
+			for i := range values {
+				values[i] = next()
+			}
+			d := newTestTDigest(t, values)
+			sort.Float64s(values)
+
+			if d.Count() != n {
+				t.Errorf("count %v, want %v", d.Count(), n)
+			}
+			for _, tt := range quantiles {
+				estimate := d.Quantile(tt.q)
+				if e := rankError(values, tt.q, estimate); e > tt.maxRank {
+					t.Errorf("q%v = %v is off by %v in rank, more than %v", tt.q, estimate, e, tt.maxRank)
+				}
+			}
+			if d.Quantile(0) != values[0] || d.Quantile(1) != values[n-1] {
+				t.Errorf("extremes %v, %v, want %v, %v", d.Quantile(0), d.Quantile(1), values[0], values[n-1])
+			}
+			// the arcsine scale keeps the digest at about compression/2 to compression centroids.
+			if c := len(d.centroids); c < DefaultCompression/2 || c > DefaultCompression {
+				t.Errorf("compressed to %d centroids, want [%d, %d]", c, DefaultCompression/2, DefaultCompression)
+			}
+		})
+	}
+}
+
+func TestTDigestSmall(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		q      float64
+		want   float64
+	}{
+		{name: "single", values: []float64{42}, q: 0.5, want: 42},
+		{name: "single p99", values: []float64{42}, q: 0.99, want: 42},
+		{name: "constant", values: []float64{7, 7, 7, 7}, q: 0.9, want: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestTDigest(t, tt.values)
+			if got := d.Quantile(tt.q); got != tt.want {
+				t.Errorf("q%v = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+
+	if q := newTestTDigest(t, nil).Quantile(0.5); !math.IsNaN(q) {
+		t.Errorf("empty digest q0.5 = %v, want NaN", q)
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	values := make([]float64, 50000)
+	for i := range values {
+		values[i] = r.ExpFloat64() * 250
+	}
+
+	a := newTestTDigest(t, values[:20000])
+	b := newTestTDigest(t, values[20000:])
+	ab := newTestTDigest(t, nil)
+	ab.Merge(a)
+	ab.Merge(b)
+	ba := newTestTDigest(t, nil)
+	ba.Merge(b)
+	ba.Merge(a)
+
+	sort.Float64s(values)
+	for _, d := range []*TDigest{ab, ba} {
+		if d.Count() != float64(len(values)) {
+			t.Errorf("count %v, want %v", d.Count(), len(values))
+		}
+		if d.Quantile(0) != values[0] || d.Quantile(1) != values[len(values)-1] {
+			t.Error("merge lost the extremes")
+		}
+		for _, q := range []float64{0.5, 0.9, 0.99} {
+			if e := rankError(values, q, d.Quantile(q)); e > 0.01 {
+				t.Errorf("merged q%v is off by %v in rank", q, e)
+			}
+		}
+	}
+}
+
+func TestTDigestMarshalRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = r.NormFloat64()*10 + 100
+	}
+	d := newTestTDigest(t, values)
+
+	data, err := d.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded TDigest
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Count() != d.Count() {
+		t.Errorf("count %v, want %v", decoded.Count(), d.Count())
+	}
+	for _, q := range []float64{0, 0.01, 0.5, 0.99, 1} {
+		if decoded.Quantile(q) != d.Quantile(q) {
+			t.Errorf("q%v = %v, want %v", q, decoded.Quantile(q), d.Quantile(q))
+		}
+	}
+
+	empty, err := newTestTDigest(t, nil).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.UnmarshalBinary(empty); err != nil || decoded.Count() != 0 {
+		t.Errorf("empty digest round trip: count %v, err %v", decoded.Count(), err)
+	}
+}
+
+func TestTDigestUnmarshalInvalid(t *testing.T) {
+	valid, err := newTestTDigest(t, []float64{1, 2, 3}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nan := append([]byte{}, valid...)
+	for i := tdigestHeaderLen; i < tdigestHeaderLen+8; i++ {
+		nan[i] = 0xFF
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "short", data: valid[:tdigestHeaderLen-1]},
+		{name: "version", data: append([]byte{9}, valid[1:]...)},
+		{name: "compression", data: append([]byte{tdigestVersion}, make([]byte, tdigestHeaderLen-1)...)},
+		{name: "truncated", data: valid[:len(valid)-1]},
+		{name: "nan centroid", data: nan},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d TDigest
+			if err := d.UnmarshalBinary(tt.data); !errors.Is(err, ErrInvalidSketch) {
+				t.Errorf("got %v, want ErrInvalidSketch", err)
+			}
+		})
+	}
+}
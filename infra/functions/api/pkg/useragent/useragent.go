@@ -0,0 +1,116 @@
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"regexp"
+	"sync"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+//go:embed rules.json
+var rulesFile []byte
+
+// Agent is what the rules could tell about a user-agent.
+// Versions are major versions only, so they group well.
+type Agent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Device         string
+	Engine         string
+}
+
+type rule struct {
+	Regex   string `json:"regex"`
+	Exclude string `json:"exclude"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Class   string `json:"class"`
+
+	re      *regexp.Regexp
+	exclude *regexp.Regexp
+}
+
+type rules struct {
+	Browsers []rule `json:"browsers"`
+	OS       []rule `json:"os"`
+	Engines  []rule `json:"engines"`
+	Devices  []rule `json:"devices"`
+}
+
+var (
+	rulesOnce sync.Once
+	parsed    *rules
+)
+
+// Parse matches the user-agent against the embedded rules; within each group the first match wins.
+// An empty user-agent yields an empty Agent, and an unknown one is classed as a desktop.
+func Parse(ua string) Agent {
+	rulesOnce.Do(loadRules)
+
+	var agent Agent
+	if ua == "" {
+		return agent
+	}
+
+	if r, version := match(parsed.Browsers, ua); r != nil {
+		agent.Browser, agent.BrowserVersion = r.Name, version
+	}
+	if r, version := match(parsed.OS, ua); r != nil {
+		agent.OS, agent.OSVersion = r.Name, version
+	}
+	if r, _ := match(parsed.Engines, ua); r != nil {
+		agent.Engine = r.Name
+	}
+
+	agent.Device = DeviceDesktop
+	if r, _ := match(parsed.Devices, ua); r != nil {
+		agent.Device = r.Class
+	}
+
+	return agent
+}
+
+func match(group []rule, ua string) (*rule, string) {
+	for i := range group {
+		r := &group[i]
+		m := r.re.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		if r.exclude != nil && r.exclude.MatchString(ua) {
+			continue
+		}
+
+		version := r.Version
+		if version == "" && len(m) > 1 {
+			version = m[1]
+		}
+		return r, version
+	}
+	return nil, ""
+}
+
+// loadRules panics on a broken rules file, since it is embedded at build time.
+func loadRules() {
+	var rs rules
+	if err := json.Unmarshal(rulesFile, &rs); err != nil {
+		panic(err)
+	}
+	for _, group := range [][]rule{rs.Browsers, rs.OS, rs.Engines, rs.Devices} {
+		for i := range group {
+			group[i].re = regexp.MustCompile(group[i].Regex)
+			if group[i].Exclude != "" {
+				group[i].exclude = regexp.MustCompile(group[i].Exclude)
+			}
+		}
+	}
+	parsed = &rs
+}
@@ -7,31 +7,75 @@ import * as lambdaGo from '@aws-cdk/aws-lambda-go-alpha';
 import * as apigw from 'aws-cdk-lib/aws-apigatewayv2';
 import * as cloudwatch from 'aws-cdk-lib/aws-cloudwatch';
 import * as dynamodb from 'aws-cdk-lib/aws-dynamodb';
+import * as sqs from 'aws-cdk-lib/aws-sqs';
+import {
+  DynamoEventSource,
+  SqsDlq,
+} from 'aws-cdk-lib/aws-lambda-event-sources';
 import { HttpLambdaIntegration } from 'aws-cdk-lib/aws-apigatewayv2-integrations';
 
 interface IProps extends cdk.StackProps {
   api: apigw.IHttpApi;
   tableName: string;
+  cursorSecret: string;
+  retention?: string;
+  topClicksIndex?: boolean;
+  visitorIndex?: boolean;
+  sessionIndex?: boolean;
+  botPolicy?: string;
+  enrichStages?: string;
 }
 
 export class ClickstreamServiceStack extends cdk.Stack {
   constructor(scope: Construct, id: string, props: IProps) {
     super(scope, id, props);
 
-    this.newClickstreamTable(props);
+    const table = this.newClickstreamTable(props);
 
     const fn = this.newClickstreamServiceFunction(props);
     this.registerClickstreamServiceRoute(fn, props.api);
+
+    this.newClickstreamWorkerFunction(props, table);
   }
 
   private newClickstreamTable(props: IProps) {
-    return new dynamodb.Table(this, 'ClickstreamTable', {
+    const table = new dynamodb.Table(this, 'ClickstreamTable', {
       tableName: props.tableName,
       partitionKey: { name: 'PK', type: dynamodb.AttributeType.STRING },
       sortKey: { name: 'SK', type: dynamodb.AttributeType.STRING },
       encryption: dynamodb.TableEncryption.AWS_MANAGED,
       billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      timeToLiveAttribute: 'expiresAt',
+      stream: dynamodb.StreamViewType.NEW_IMAGE,
     });
+    // DynamoDB creates only one GSI per table update, so every index can be left out of a deploy,
+    // see `[table.clickstream]` in config/dev.toml for the rollout order over an existing table.
+    if (props.topClicksIndex !== false) {
+      table.addGlobalSecondaryIndex({
+        indexName: 'TopClicksIndex',
+        partitionKey: { name: 'PK', type: dynamodb.AttributeType.STRING },
+        sortKey: { name: 'clicks', type: dynamodb.AttributeType.NUMBER },
+        projectionType: dynamodb.ProjectionType.INCLUDE,
+        nonKeyAttributes: ['path', 'period'],
+      });
+    }
+    if (props.visitorIndex !== false) {
+      table.addGlobalSecondaryIndex({
+        indexName: 'VisitorIndex',
+        partitionKey: { name: 'visitorId', type: dynamodb.AttributeType.STRING },
+        sortKey: { name: 'SK', type: dynamodb.AttributeType.STRING },
+        projectionType: dynamodb.ProjectionType.ALL,
+      });
+    }
+    if (props.sessionIndex !== false) {
+      table.addGlobalSecondaryIndex({
+        indexName: 'SessionIndex',
+        partitionKey: { name: 'sessionId', type: dynamodb.AttributeType.STRING },
+        sortKey: { name: 'SK', type: dynamodb.AttributeType.STRING },
+        projectionType: dynamodb.ProjectionType.ALL,
+      });
+    }
+    return table;
   }
 
   private newClickstreamServiceFunction(props: IProps) {
@@ -49,12 +93,26 @@ export class ClickstreamServiceStack extends cdk.Stack {
       ),
       runtime: lambda.Runtime.PROVIDED_AL2023,
       architecture: lambda.Architecture.ARM_64,
-      timeout: cdk.Duration.seconds(5),
+      // ControllerTimeout (10s) must end before the function is killed, so partial results are returned
+      // instead of a 502. It also matches the idempotency lease, ControllerTimeout + IdempotencyLeaseMargin.
+      timeout: cdk.Duration.seconds(15),
       bundling: {
         goBuildFlags: ['-ldflags "-s -w"'],
+        // bundles the geoip database next to the binary when it is present.
+        commandHooks: {
+          beforeBundling: () => [],
+          afterBundling: (inputDir: string, outputDir: string) => [
+            `if [ -f ${inputDir}/data/GeoLite2-City.mmdb ]; then cp ${inputDir}/data/GeoLite2-City.mmdb ${outputDir}/; fi`,
+          ],
+        },
       },
       environment: {
         AWS_XRAY_TRACING_NAME: 'ClickStreamService',
+        CURSOR_SECRET: props.cursorSecret,
+        CLICKSTREAM_RETENTION: props.retention || '',
+        BOT_POLICY: props.botPolicy || 'flag',
+        GEOIP_DB_PATH: '/var/task/GeoLite2-City.mmdb',
+        ...(props.enrichStages ? { ENRICH_STAGES: props.enrichStages } : {}),
       },
     });
     fn.addToRolePolicy(
@@ -66,6 +124,49 @@ export class ClickstreamServiceStack extends cdk.Stack {
           'dynamodb:PutItem',
           'dynamodb:UpdateItem',
           'dynamodb:DeleteItem',
+          'dynamodb:BatchWriteItem',
+        ],
+        resources: [
+          `arn:aws:dynamodb:${this.region}:${this.account}:table/${props.tableName}`,
+          `arn:aws:dynamodb:${this.region}:${this.account}:table/${props.tableName}/index/*`,
+        ],
+      })
+    );
+    fn.addToRolePolicy(
+      new iam.PolicyStatement({
+        actions: ['xray:*'],
+        resources: ['*'],
+      })
+    );
+    cloudwatch.Metric.grantPutMetricData(fn);
+    return fn;
+  }
+
+  // the worker consumes the table stream for the work that does not fit in a request.
+  private newClickstreamWorkerFunction(props: IProps, table: dynamodb.ITable) {
+    const ns = this.node.tryGetContext('ns') as string;
+
+    const fn = new lambdaGo.GoFunction(this, 'ClickstreamWorker', {
+      functionName: `${ns}ClickstreamWorker`,
+      entry: path.resolve(__dirname, '..', 'functions', 'api', 'cmd', 'worker'),
+      runtime: lambda.Runtime.PROVIDED_AL2023,
+      architecture: lambda.Architecture.ARM_64,
+      timeout: cdk.Duration.minutes(1),
+      tracing: lambda.Tracing.ACTIVE,
+      bundling: {
+        goBuildFlags: ['-ldflags "-s -w"'],
+      },
+      environment: {
+        AWS_XRAY_TRACING_NAME: 'ClickStreamWorker',
+      },
+    });
+    fn.addToRolePolicy(
+      new iam.PolicyStatement({
+        actions: [
+          'dynamodb:Query',
+          'dynamodb:GetItem',
+          'dynamodb:UpdateItem',
+          'dynamodb:BatchWriteItem',
         ],
         resources: [
           `arn:aws:dynamodb:${this.region}:${this.account}:table/${props.tableName}`,
@@ -80,6 +181,60 @@ export class ClickstreamServiceStack extends cdk.Stack {
       })
     );
     cloudwatch.Metric.grantPutMetricData(fn);
+
+    // records that still fail after the retries are sent here instead of being dropped silently.
+    // An erasure among them stops at its last pass, a new request for its subject erases the rest.
+    const dlq = new sqs.Queue(this, 'ClickstreamWorkerDlq', {
+      retentionPeriod: cdk.Duration.days(14),
+      encryption: sqs.QueueEncryption.SQS_MANAGED,
+    });
+    new cloudwatch.Alarm(this, 'ClickstreamWorkerDlqAlarm', {
+      alarmDescription:
+        'Table stream records failed after all retries, see the ClickstreamWorkerDlq messages',
+      metric: dlq.metricApproximateNumberOfMessagesVisible({
+        period: cdk.Duration.minutes(5),
+      }),
+      threshold: 1,
+      evaluationPeriods: 1,
+      comparisonOperator:
+        cloudwatch.ComparisonOperator.GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+      treatMissingData: cloudwatch.TreatMissingData.NOT_BREACHING,
+    });
+
+    fn.addEventSource(
+      new DynamoEventSource(table, {
+        startingPosition: lambda.StartingPosition.TRIM_HORIZON,
+        batchSize: 100,
+        retryAttempts: 10,
+        onFailure: new SqsDlq(dlq),
+        reportBatchItemFailures: true,
+        filters: [
+          // erasure receipts, every pass updates its receipt and so schedules the next one.
+          lambda.FilterCriteria.filter({
+            eventName: lambda.FilterRule.or('INSERT', 'MODIFY'),
+            dynamodb: {
+              Keys: {
+                PK: { S: lambda.FilterRule.beginsWith('CLICK#ERASURE#') },
+              },
+              NewImage: { status: { S: lambda.FilterRule.isEqual('running') } },
+            },
+          }),
+          // new clicks of visitors, their unique visitors and dwell times are merged once per batch.
+          lambda.FilterCriteria.filter({
+            eventName: lambda.FilterRule.isEqual('INSERT'),
+            dynamodb: {
+              Keys: {
+                PK: { S: lambda.FilterRule.beginsWith('CLICK#EVENT#PATH#') },
+              },
+              NewImage: {
+                isBot: { BOOL: lambda.FilterRule.notExists() },
+              },
+            },
+          }),
+        ],
+        maxBatchingWindow: cdk.Duration.seconds(10),
+      })
+    );
     return fn;
   }
 
@@ -108,5 +263,27 @@ export class ClickstreamServiceStack extends cdk.Stack {
       ),
       integration,
     });
+    new apigw.HttpRoute(this, 'SessionServiceRouteV1', {
+      httpApi,
+      routeKey: apigw.HttpRouteKey.with('/v1/sessions', apigw.HttpMethod.GET),
+      integration,
+    });
+    new apigw.HttpRoute(this, 'CorsSessionServiceRouteV1', {
+      httpApi,
+      routeKey: apigw.HttpRouteKey.with(
+        '/v1/sessions/{proxy+}',
+        apigw.HttpMethod.OPTIONS
+      ),
+      integration,
+      authorizer: new apigw.HttpNoneAuthorizer(),
+    });
+    new apigw.HttpRoute(this, 'SessionServiceDetailRouteV1', {
+      httpApi,
+      routeKey: apigw.HttpRouteKey.with(
+        '/v1/sessions/{proxy+}',
+        apigw.HttpMethod.GET
+      ),
+      integration,
+    });
   }
 }
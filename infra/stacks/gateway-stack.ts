@@ -62,6 +62,7 @@ export class GatewayStack extends cdk.Stack {
           'Content-Type',
           'X-Amzn-Trace-Id',
           'X-Requested-With',
+          'Idempotency-Key',
         ],
         allowCredentials: false,
         maxAge: cdk.Duration.days(1),
@@ -0,0 +1,83 @@
+import * as cdk from 'aws-cdk-lib';
+import * as apigw from 'aws-cdk-lib/aws-apigatewayv2';
+import { Match, Template } from 'aws-cdk-lib/assertions';
+import { ClickstreamServiceStack } from '../stacks/clickstream-service-stack';
+
+function synth() {
+  const app = new cdk.App({
+    context: {
+      ns: 'Test',
+      stage: 'test',
+      // the functions are not bundled, only the template is asserted.
+      'aws:cdk:bundling-stacks': [],
+    },
+  });
+  const apiStack = new cdk.Stack(app, 'ApiStack');
+  const api = apigw.HttpApi.fromHttpApiAttributes(apiStack, 'Api', {
+    httpApiId: 'api',
+  });
+  const stack = new ClickstreamServiceStack(app, 'ClickStreamServiceStack', {
+    api,
+    tableName: 'clickstream',
+    cursorSecret: 'secret',
+  });
+  return Template.fromStack(stack);
+}
+
+function streamFilterPatterns(template: Template) {
+  const mappings = template.findResources('AWS::Lambda::EventSourceMapping');
+  return Object.values(mappings).flatMap((mapping) =>
+    mapping.Properties.FilterCriteria.Filters.map(
+      (filter: { Pattern: string }) => JSON.parse(filter.Pattern)
+    )
+  );
+}
+
+describe('ClickstreamServiceStack', () => {
+  test('worker receives running erasures', () => {
+    const patterns = streamFilterPatterns(synth());
+
+    expect(patterns).toContainEqual({
+      eventName: ['INSERT', 'MODIFY'],
+      dynamodb: {
+        Keys: { PK: { S: [{ prefix: 'CLICK#ERASURE#' }] } },
+        NewImage: { status: { S: ['running'] } },
+      },
+    });
+  });
+
+  test('worker receives new clicks of visitors', () => {
+    const patterns = streamFilterPatterns(synth());
+
+    // stream images wrap every value in its type, so exists matches the typed leaf.
+    expect(patterns).toContainEqual({
+      eventName: ['INSERT'],
+      dynamodb: {
+        Keys: { PK: { S: [{ prefix: 'CLICK#EVENT#PATH#' }] } },
+        NewImage: {
+          isBot: { BOOL: [{ exists: false }] },
+        },
+      },
+    });
+  });
+
+  test('worker sends exhausted records to an alarmed queue', () => {
+    const template = synth();
+
+    template.hasResourceProperties('AWS::Lambda::EventSourceMapping', {
+      MaximumRetryAttempts: 10,
+      DestinationConfig: {
+        OnFailure: {
+          Destination: {
+            'Fn::GetAtt': [Match.stringLikeRegexp('^ClickstreamWorkerDlq'), 'Arn'],
+          },
+        },
+      },
+    });
+    template.hasResourceProperties('AWS::CloudWatch::Alarm', {
+      MetricName: 'ApproximateNumberOfMessagesVisible',
+      Namespace: 'AWS/SQS',
+      Threshold: 1,
+    });
+  });
+});